@@ -36,7 +36,7 @@ describe('Load video from disk', () => {
     const fixturesFolder = Cypress.config('fixturesFolder');
     const folderPath = `${fixturesFolder}/videos_empty`;
     cy.addVideoFromDisk('test-load-disk', folderPath)
-    cy.contains('folder does not contain .mp4 or .webm files').should('be.visible')
+    cy.contains('folder does not contain video files').should('be.visible')
   });
 
   it('does not add videos that already exist', () => {
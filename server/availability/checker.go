@@ -0,0 +1,156 @@
+package availability
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+	customErrors "vidviewer/errors"
+	"vidviewer/models"
+	"vidviewer/ytdlp"
+)
+
+// How often the checker looks for videos that are due
+const DefaultPollInterval = 1 * time.Hour
+
+// How long a video's availability is trusted before it is checked again
+const DefaultCheckInterval = 7 * 24 * time.Hour
+
+// Pause between two probes, to be polite to the sites
+const DefaultDelay = 10 * time.Second
+
+// Videos probed per poll
+const DefaultBatchSize = 50
+
+// The videos repository, satisfied by *repository.VideoRepository
+type VideoStore interface {
+	GetAvailabilityDue(checkedBefore int64, limit int) ([]models.Video, error)
+	SetAvailability(id int64, availability string, checkedAt int64) error
+}
+
+// Re-probes the url of downloaded videos with yt-dlp
+// and records whether they are still available
+type Checker struct {
+	videos VideoStore
+	// Returns nil if the url is available, ytdlp.CheckAvailability by default
+	Probe         func(url string) error
+	PollInterval  time.Duration
+	CheckInterval time.Duration
+	Delay         time.Duration
+	BatchSize     int
+	once          sync.Once
+	mutex         sync.Mutex // only one batch runs at a time
+}
+
+func NewChecker(videos VideoStore) *Checker {
+	return &Checker{
+		videos:        videos,
+		Probe:         ytdlp.CheckAvailability,
+		PollInterval:  DefaultPollInterval,
+		CheckInterval: DefaultCheckInterval,
+		Delay:         DefaultDelay,
+		BatchSize:     DefaultBatchSize,
+	}
+}
+
+// Starts checking in the background, calling it again does nothing.
+// The repository needs a database connection before this is called.
+func (c *Checker) Start() {
+	c.once.Do(func() {
+		go func() {
+			ticker := time.NewTicker(c.PollInterval)
+			for ; true; <-ticker.C {
+				// Checked again once yt-dlp is installed
+				if ytdlp.IsInstalled() {
+					c.CheckDue()
+				}
+			}
+		}()
+	})
+}
+
+// Probes the videos that were not checked within CheckInterval,
+// least recently checked first. Returns the number of videos updated.
+func (c *Checker) CheckDue() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	videos, err := c.videos.GetAvailabilityDue(time.Now().Add(-c.CheckInterval).Unix(), c.BatchSize)
+
+	if err != nil {
+		log.Println("Error getting videos to check:", err)
+		return 0
+	}
+
+	updated := 0
+
+	for i, video := range videos {
+		if i > 0 {
+			time.Sleep(c.Delay)
+		}
+
+		err := c.Probe(video.Url)
+		reason := errorReason(err)
+
+		availability, ok := availabilityOf(err, reason)
+
+		// Keep the last known status but don't retry the video before the
+		// check interval, a dead host is not checked first on every poll
+		if !ok {
+			log.Println("Error checking availability:", video.Url, err)
+			availability = video.Availability
+		}
+
+		err = c.videos.SetAvailability(video.ID, availability, time.Now().Unix())
+
+		if err != nil {
+			log.Println("Error saving availability:", err)
+		} else {
+			updated++
+		}
+
+		// Stop hitting the site until the next poll
+		if reason == customErrors.ReasonRateLimited {
+			log.Println("Rate limited checking availability:", video.Url)
+			break
+		}
+	}
+
+	return updated
+}
+
+// The reason of a classified yt-dlp error, "" if err is nil
+func errorReason(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var downloadErr *customErrors.Error
+	if errors.As(err, &downloadErr) && downloadErr.Reason != "" {
+		return downloadErr.Reason
+	}
+
+	return customErrors.ReasonUnknown
+}
+
+// Maps the probe result to a models.Availability value,
+// ok is false if it says nothing about the video
+func availabilityOf(err error, reason string) (availability string, ok bool) {
+	if err == nil {
+		return models.AvailabilityAvailable, true
+	}
+
+	switch reason {
+	case customErrors.ReasonRemoved:
+		return models.AvailabilityRemoved, true
+	case customErrors.ReasonPrivate:
+		return models.AvailabilityPrivate, true
+	case customErrors.ReasonGeoBlocked, customErrors.ReasonAgeRestricted:
+		return models.AvailabilityRestricted, true
+	// The video is there, only the format it was downloaded in is gone
+	case customErrors.ReasonFormatUnavailable:
+		return models.AvailabilityAvailable, true
+	}
+
+	return "", false
+}
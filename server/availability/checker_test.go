@@ -0,0 +1,152 @@
+package availability
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"vidviewer/models"
+)
+
+// Prints the error yt-dlp would for the urls below, other urls are available
+const stubYtdlp = `#!/bin/sh
+for url; do :; done
+case "$url" in
+  *removed*) echo "ERROR: [youtube] abc: Video unavailable. This video has been removed by the uploader" >&2; exit 1 ;;
+  *private*) echo "ERROR: [youtube] abc: Private video. Sign in if you've been granted access to this video" >&2; exit 1 ;;
+  *blocked*) echo "ERROR: [youtube] abc: The uploader has not made this video available in your country" >&2; exit 1 ;;
+  *offline*) echo "ERROR: [youtube] abc: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>" >&2; exit 1 ;;
+  *limited*) echo "ERROR: [youtube] abc: Unable to download webpage: HTTP Error 429: Too Many Requests" >&2; exit 1 ;;
+  *broken*) echo "ERROR: [generic] something unexpected" >&2; exit 1 ;;
+esac
+exit 0
+`
+
+// Puts the stub first in PATH so the checker runs it instead of yt-dlp
+func useStubYtdlp(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "yt-dlp"), []byte(stubYtdlp), 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to write yt-dlp stub: %s\n", err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+type fakeVideoStore struct {
+	videos []models.Video
+}
+
+func (s *fakeVideoStore) GetAvailabilityDue(checkedBefore int64, limit int) ([]models.Video, error) {
+	due := []models.Video{}
+
+	for _, video := range s.videos {
+		if video.AvailabilityCheckedAt < checkedBefore && len(due) < limit {
+			due = append(due, video)
+		}
+	}
+
+	return due, nil
+}
+
+func (s *fakeVideoStore) SetAvailability(id int64, availability string, checkedAt int64) error {
+	for i := range s.videos {
+		if s.videos[i].ID == id {
+			s.videos[i].Availability = availability
+			s.videos[i].AvailabilityCheckedAt = checkedAt
+		}
+	}
+
+	return nil
+}
+
+func (s *fakeVideoStore) get(id int64) models.Video {
+	for _, video := range s.videos {
+		if video.ID == id {
+			return video
+		}
+	}
+
+	return models.Video{}
+}
+
+func newTestChecker(store *fakeVideoStore) *Checker {
+	checker := NewChecker(store)
+	checker.Delay = 0
+	return checker
+}
+
+func TestCheckDue(t *testing.T) {
+	useStubYtdlp(t)
+
+	store := &fakeVideoStore{videos: []models.Video{
+		{ID: 1, Url: "https://example.com/available"},
+		{ID: 2, Url: "https://example.com/removed"},
+		{ID: 3, Url: "https://example.com/private"},
+		{ID: 4, Url: "https://example.com/blocked"},
+		{ID: 5, Url: "https://example.com/offline"},
+		{ID: 6, Url: "https://example.com/broken", Availability: models.AvailabilityRemoved},
+	}}
+
+	checker := newTestChecker(store)
+
+	updated := checker.CheckDue()
+
+	if updated != 6 {
+		t.Errorf("Expected 6 videos to be updated, got %d", updated)
+	}
+
+	expected := map[int64]string{
+		1: models.AvailabilityAvailable,
+		2: models.AvailabilityRemoved,
+		3: models.AvailabilityPrivate,
+		4: models.AvailabilityRestricted,
+		5: models.AvailabilityUnknown,
+		6: models.AvailabilityRemoved,
+	}
+
+	for id, availability := range expected {
+		if video := store.get(id); video.Availability != availability {
+			t.Errorf("Expected video %d to be %q, got %q", id, availability, video.Availability)
+		}
+	}
+
+	// Not checked first again on the next poll
+	if store.get(5).AvailabilityCheckedAt == 0 {
+		t.Errorf("Expected a network error to mark the video as checked")
+	}
+
+	if store.get(6).AvailabilityCheckedAt == 0 {
+		t.Errorf("Expected an unknown error to mark the video as checked")
+	}
+
+	// No video is due again
+	if updated := checker.CheckDue(); updated != 0 || store.get(1).AvailabilityCheckedAt == 0 {
+		t.Errorf("Expected no videos to be updated, got %d", updated)
+	}
+}
+
+func TestCheckDueStopsWhenRateLimited(t *testing.T) {
+	useStubYtdlp(t)
+
+	store := &fakeVideoStore{videos: []models.Video{
+		{ID: 1, Url: "https://example.com/available"},
+		{ID: 2, Url: "https://example.com/limited"},
+		{ID: 3, Url: "https://example.com/removed"},
+	}}
+
+	updated := newTestChecker(store).CheckDue()
+
+	if updated != 2 {
+		t.Errorf("Expected 2 videos to be updated, got %d", updated)
+	}
+
+	if store.get(2).AvailabilityCheckedAt == 0 {
+		t.Errorf("Expected the rate limited video to be marked as checked")
+	}
+
+	if store.get(3).AvailabilityCheckedAt != 0 {
+		t.Errorf("Expected the videos after the rate limit not to be checked")
+	}
+}
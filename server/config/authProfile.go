@@ -0,0 +1,289 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	AuthProfileCookies = "cookies"
+	AuthProfileNetrc   = "netrc"
+)
+
+// Credentials passed to yt-dlp for the sites matching Domains.
+// The secrets (cookies.txt content, netrc password) are only
+// written to the profile's file, never to auth_profiles.yaml.
+type AuthProfile struct {
+	Name string `yaml:"name" json:"name"`
+	Type string `yaml:"type" json:"type"` // AuthProfileCookies or AuthProfileNetrc
+	// eg. "youtube.com", matches the domain and its subdomains.
+	// A leading "*." is allowed and means the same.
+	Domains []string `yaml:"domains" json:"domains"`
+	// netrc only, the yt-dlp extractor name, eg. "youtube"
+	Machine  string `yaml:"machine" json:"machine,omitempty"`
+	Username string `yaml:"username" json:"username,omitempty"`
+}
+
+var authProfileNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Guards auth_profiles.yaml and the profile files
+var authProfilesMutex sync.Mutex
+
+func getAuthProfilesFilePath() string {
+	return filepath.Join(getRootPath(), "auth_profiles.yaml")
+}
+
+func getAuthFolderPath() string {
+	return filepath.Join(getRootPath(), "auth")
+}
+
+// Path of the cookies.txt or netrc file of the profile
+func (p AuthProfile) SecretPath() string {
+	ext := ".cookies.txt"
+	if p.Type == AuthProfileNetrc {
+		ext = ".netrc"
+	}
+	return filepath.Join(getAuthFolderPath(), p.Name+ext)
+}
+
+func (p AuthProfile) HasSecret() bool {
+	_, err := os.Stat(p.SecretPath())
+	return err == nil
+}
+
+func (p AuthProfile) Validate() error {
+	if !authProfileNameRegex.MatchString(p.Name) {
+		return errors.New("name can only contain letters, numbers, - and _")
+	}
+
+	if p.Type != AuthProfileCookies && p.Type != AuthProfileNetrc {
+		return fmt.Errorf("type must be %s or %s", AuthProfileCookies, AuthProfileNetrc)
+	}
+
+	if len(p.Domains) == 0 {
+		return errors.New("at least one domain is required")
+	}
+
+	for _, domain := range p.Domains {
+		if normalizeDomain(domain) == "" || strings.ContainsAny(domain, "/: ") {
+			return fmt.Errorf("invalid domain %q", domain)
+		}
+	}
+
+	if p.Type == AuthProfileNetrc && (p.Machine == "" || p.Username == "" || strings.ContainsAny(p.Machine+p.Username, " \t\n")) {
+		return errors.New("netrc profiles need a machine and a username without spaces")
+	}
+
+	return nil
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "*.")
+}
+
+// Length of the longest domain of the profile matching host, 0 if none match
+func (p AuthProfile) match(host string) int {
+	longest := 0
+
+	for _, domain := range p.Domains {
+		domain = normalizeDomain(domain)
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && len(domain) > longest {
+			longest = len(domain)
+		}
+	}
+
+	return longest
+}
+
+func LoadAuthProfiles() ([]AuthProfile, error) {
+	authProfilesMutex.Lock()
+	defer authProfilesMutex.Unlock()
+
+	return loadAuthProfiles()
+}
+
+func loadAuthProfiles() ([]AuthProfile, error) {
+	profiles := []AuthProfile{}
+
+	data, err := os.ReadFile(getAuthProfilesFilePath())
+
+	if os.IsNotExist(err) {
+		return profiles, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(data, &profiles)
+
+	return profiles, err
+}
+
+func saveAuthProfiles(profiles []AuthProfile) error {
+	data, err := yaml.Marshal(profiles)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(getAuthProfilesFilePath(), data, 0600)
+}
+
+// Returns nil if the profile does not exist
+func GetAuthProfile(name string) (*AuthProfile, error) {
+	profiles, err := LoadAuthProfiles()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return &profile, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Creates or replaces the profile. secret is the cookies.txt content
+// or the netrc password, an empty secret keeps the existing file.
+func SaveAuthProfile(profile AuthProfile, secret string) error {
+	authProfilesMutex.Lock()
+	defer authProfilesMutex.Unlock()
+
+	profiles, err := loadAuthProfiles()
+
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i := range profiles {
+		if profiles[i].Name == profile.Name {
+			index = i
+		}
+	}
+
+	if index == -1 && secret == "" {
+		return errors.New("secret is required for new profiles")
+	}
+
+	err = os.MkdirAll(getAuthFolderPath(), 0700)
+
+	if err != nil {
+		return err
+	}
+
+	if index != -1 && profiles[index].Type != profile.Type {
+		if secret == "" {
+			return errors.New("secret is required when changing the type")
+		}
+		os.Remove(profiles[index].SecretPath())
+	}
+
+	if profile.Type == AuthProfileNetrc && secret == "" {
+		// Rewrite the entry with the current password for a new machine or username
+		secret, err = readNetrcPassword(profile.SecretPath())
+		if err != nil {
+			return err
+		}
+	}
+
+	if secret != "" {
+		content := secret
+		if profile.Type == AuthProfileNetrc {
+			content = fmt.Sprintf("machine %s login %s password %s\n", profile.Machine, profile.Username, secret)
+		}
+
+		err = os.WriteFile(profile.SecretPath(), []byte(content), 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	if index == -1 {
+		profiles = append(profiles, profile)
+	} else {
+		profiles[index] = profile
+	}
+
+	return saveAuthProfiles(profiles)
+}
+
+func readNetrcPassword(path string) (string, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(data))
+
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "password" {
+			return fields[i+1], nil
+		}
+	}
+
+	return "", errors.New("netrc file has no password")
+}
+
+// Deletes the profile and its file, returns false if it does not exist
+func DeleteAuthProfile(name string) (bool, error) {
+	authProfilesMutex.Lock()
+	defer authProfilesMutex.Unlock()
+
+	profiles, err := loadAuthProfiles()
+
+	if err != nil {
+		return false, err
+	}
+
+	for i, profile := range profiles {
+		if profile.Name != name {
+			continue
+		}
+
+		os.Remove(profile.SecretPath())
+
+		return true, saveAuthProfiles(append(profiles[:i], profiles[i+1:]...))
+	}
+
+	return false, nil
+}
+
+// Returns the profile with the most specific domain
+// matching the host of the url, or nil if none match
+func MatchAuthProfile(rawUrl string) *AuthProfile {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+
+	profiles, err := LoadAuthProfiles()
+	if err != nil {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+
+	var match *AuthProfile
+	longest := 0
+
+	for i := range profiles {
+		if length := profiles[i].match(host); length > longest {
+			match = &profiles[i]
+			longest = length
+		}
+	}
+
+	return match
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func initializeTestConfig(t *testing.T) {
+	isTestMode = true
+	os.RemoveAll(getRootPath())
+
+	err := os.MkdirAll(getRootPath(), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(getRootPath()) })
+}
+
+func TestAuthProfiles(t *testing.T) {
+	initializeTestConfig(t)
+
+	err := SaveAuthProfile(AuthProfile{Name: "google", Type: AuthProfileCookies, Domains: []string{"google.com"}}, "cookie-secret")
+	if err != nil {
+		t.Fatalf("Error saving profile: %s", err)
+	}
+
+	err = SaveAuthProfile(AuthProfile{Name: "youtube", Type: AuthProfileNetrc, Domains: []string{"*.youtube.com", "youtu.be"}, Machine: "youtube", Username: "user"}, "netrc-secret")
+	if err != nil {
+		t.Fatalf("Error saving profile: %s", err)
+	}
+
+	if profile := MatchAuthProfile("https://www.youtube.com/watch?v=abc"); profile == nil || profile.Name != "youtube" {
+		t.Errorf("Expected the youtube profile, got %+v", profile)
+	}
+
+	if profile := MatchAuthProfile("https://youtu.be/abc"); profile == nil || profile.Name != "youtube" {
+		t.Errorf("Expected the youtube profile, got %+v", profile)
+	}
+
+	if profile := MatchAuthProfile("https://notyoutube.com/abc"); profile != nil {
+		t.Errorf("Expected no profile, got %+v", profile)
+	}
+
+	// Secrets are only written to the profile files
+	data, _ := os.ReadFile(getAuthProfilesFilePath())
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Expected no secrets in auth_profiles.yaml, got %s", data)
+	}
+
+	// Keeps the password when the username changes
+	err = SaveAuthProfile(AuthProfile{Name: "youtube", Type: AuthProfileNetrc, Domains: []string{"youtube.com"}, Machine: "youtube", Username: "other"}, "")
+	if err != nil {
+		t.Fatalf("Error updating profile: %s", err)
+	}
+
+	profile, _ := GetAuthProfile("youtube")
+	netrc, _ := os.ReadFile(profile.SecretPath())
+	if string(netrc) != "machine youtube login other password netrc-secret\n" {
+		t.Errorf("Unexpected netrc file: %s", netrc)
+	}
+
+	deleted, err := DeleteAuthProfile("youtube")
+	if !deleted || err != nil {
+		t.Fatalf("Error deleting profile: %v", err)
+	}
+
+	if profile.HasSecret() {
+		t.Error("Expected the netrc file to be deleted")
+	}
+
+	profiles, _ := LoadAuthProfiles()
+	if len(profiles) != 1 || profiles[0].Name != "google" {
+		t.Errorf("Expected only the google profile, got %+v", profiles)
+	}
+}
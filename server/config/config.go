@@ -1,161 +1,262 @@
-package config
-
-import (
-	"fmt"
-	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
-
-	"gopkg.in/yaml.v2"
-
-	_ "modernc.org/sqlite"
-)
-
-type Config struct {
-	FolderPath string `yaml:"folderPath" json:"folder_path"`
-}
-
-var isTestMode bool = false
-
-// The config directory
-func getRootPath() string {
-	path, _ := os.UserConfigDir()
-	if isTestMode {
-		return filepath.Join(path, "vidviewer-testdata")
-	} else {
-		return filepath.Join(path, "vidviewer")
-	}
-}
-
-func GetSSLCertPath() string {
-	return filepath.Join(getRootPath(), "localhost.pem")
-}
-
-func GetSSlKeyPath() string {
-	return filepath.Join(getRootPath(), "localhost-key.pem")
-}
-
-func initializeSSLCert() error {
-    var path = getRootPath()
-	// Check if the mkcert command is available in the system's PATH
-	_, err := exec.LookPath("mkcert")
-
-	if err != nil {
-		return err
-	}
-
-	// Check if the certificate files already exist in the config directory
-	_, certExists := os.Stat(path + "/localhost.pem")
-	_, keyExists := os.Stat(path + "/localhost-key.pem")
-
-	if os.IsNotExist(certExists) || os.IsNotExist(keyExists) {
-		// Run the mkcert command to generate the certificate files
-		cmd := exec.Command("mkcert", "localhost")
-		cmd.Dir = path
-		err := cmd.Run()
-		if err != nil {
-		  return err
-		}
-	}
-
-	return nil
-}
-
-func getConfigFilePath() string {
-	return filepath.Join(getRootPath(), "config.yaml")
-}
-
-func Update(newConfig Config) {
-	data, err := yaml.Marshal(&newConfig)
-
-	if err != nil {
-		log.Println("Error converting config to yaml")
-		log.Fatal(err)
-	}
-
-	err = os.WriteFile(getConfigFilePath(), data, 0644)
-
-	if err != nil {
-		log.Println("Error writing file")
-		log.Fatal(err)
-	}
-}
-
-func Path() string {
-	return getConfigFilePath()
-}
-
-func Initialize(_isTestMode bool) {
-	isTestMode = _isTestMode
-
-	if isTestMode {
-		err := os.RemoveAll(getRootPath())
-		if err != nil {
-			log.Fatalf("Failed to delete config test directory: %s", err)
-		}	
-	}
-
-	// Create root config folder if it doesn't exist
-	if _, err := os.Stat(getRootPath()); err != nil && os.IsNotExist(err) {
-		err := os.Mkdir(getRootPath(), os.ModePerm)
-		if err != nil {
-			log.Println("ERROR making config directory")
-			log.Println(err)
-		}
-	} else if err != nil {
-		fmt.Println("Error trying to get root path info: ", err)
-		panic(err)
-	}
-
-	error := initializeSSLCert()
-	if (error != nil) {
-		log.Fatal(
-			"Error initializing ssl certification:", 
-			error.Error(),
-		)
-	}
-}
-
-// Load, or create config.yaml if necessary
-func Load() Config {
-	filePath := getConfigFilePath()
-
-	// Create root config folder if it doesn't exist
-	if _, err := os.Stat(getRootPath()); err != nil && os.IsNotExist(err) {
-		err := os.Mkdir(getRootPath(), os.ModePerm)
-		if err != nil {
-			log.Println("ERROR making config directory")
-			log.Println(err)
-		}
-	} else if err != nil {
-		fmt.Println("Error trying to get root path info: ", err)
-		panic(err)
-	}
-
-	// Check if the config file exists
-	// and create it if it doesn't
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		Update(Config{FolderPath: ""})
-	}
-
-	// NOTE this is being called on every request 
-	// Read/update from memory instead?
-	data, err := os.ReadFile(filePath)
-
-	if err != nil {
-		log.Println("Error reading config file!")
-		panic(err)
-	}
-
-	config := Config{}
-
-	err = yaml.Unmarshal(data, &config)
-
-	if err != nil {
-		log.Println("Error converting yaml to Config")
-		panic(err)
-	}
-
-	return config
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	_ "modernc.org/sqlite"
+)
+
+type Config struct {
+	FolderPath string `yaml:"folderPath" json:"folder_path"`
+	MaxConcurrentDownloads int `yaml:"maxConcurrentDownloads" json:"max_concurrent_downloads"`
+	MaxDownloadAttempts int `yaml:"maxDownloadAttempts" json:"max_download_attempts"`
+	RetryBackoffSeconds int `yaml:"retryBackoffSeconds" json:"retry_backoff_seconds"`
+	// Bytes per second shared by all downloads, 0 is unlimited
+	RateLimit int64 `yaml:"rateLimit" json:"rate_limit"`
+	// Replaces RateLimit during the windows, the first matching window is used
+	RateLimitSchedule []RateLimitWindow `yaml:"rateLimitSchedule" json:"rate_limit_schedule"`
+	// SponsorBlock mode of new downloads ("mark" or "remove"), empty is disabled
+	SponsorBlockMode string `yaml:"sponsorBlockMode" json:"sponsorblock_mode"`
+}
+
+// Rate limit between Start and End ("15:04", local time).
+// A window that ends before it starts spans midnight.
+type RateLimitWindow struct {
+	Start string `yaml:"start" json:"start"`
+	End string `yaml:"end" json:"end"`
+	RateLimit int64 `yaml:"rateLimit" json:"rate_limit"` // bytes per second, 0 is unlimited
+}
+
+const timeOfDayFormat = "15:04"
+
+// Minutes since midnight
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse(timeOfDayFormat, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return t.Hour() * 60 + t.Minute(), nil
+}
+
+func (w RateLimitWindow) Validate() error {
+	if _, err := parseTimeOfDay(w.Start); err != nil {
+		return err
+	}
+
+	if _, err := parseTimeOfDay(w.End); err != nil {
+		return err
+	}
+
+	if w.RateLimit < 0 {
+		return fmt.Errorf("rate limit cannot be negative")
+	}
+
+	return nil
+}
+
+func (w RateLimitWindow) Contains(now time.Time) bool {
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return false
+	}
+
+	end, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return false
+	}
+
+	minutes := now.Hour() * 60 + now.Minute()
+
+	if start <= end {
+		return minutes >= start && minutes < end
+	}
+
+	return minutes >= start || minutes < end
+}
+
+// The global rate limit at the time, from the schedule or RateLimit
+func CurrentRateLimit(rateLimit int64, schedule []RateLimitWindow, now time.Time) int64 {
+	for _, window := range schedule {
+		if window.Contains(now) {
+			return window.RateLimit
+		}
+	}
+
+	return rateLimit
+}
+
+// Defaults used for values not set in config.yaml
+const (
+	DefaultMaxConcurrentDownloads = 2
+	DefaultMaxDownloadAttempts = 3
+	// Delay before the first retry, doubled after each failed attempt
+	DefaultRetryBackoffSeconds = 10
+)
+
+var isTestMode bool = false
+
+// The config directory
+func getRootPath() string {
+	path, _ := os.UserConfigDir()
+	if isTestMode {
+		return filepath.Join(path, "vidviewer-testdata")
+	} else {
+		return filepath.Join(path, "vidviewer")
+	}
+}
+
+func GetSSLCertPath() string {
+	return filepath.Join(getRootPath(), "localhost.pem")
+}
+
+func GetSSlKeyPath() string {
+	return filepath.Join(getRootPath(), "localhost-key.pem")
+}
+
+func initializeSSLCert() error {
+    var path = getRootPath()
+	// Check if the mkcert command is available in the system's PATH
+	_, err := exec.LookPath("mkcert")
+
+	if err != nil {
+		return err
+	}
+
+	// Check if the certificate files already exist in the config directory
+	_, certExists := os.Stat(path + "/localhost.pem")
+	_, keyExists := os.Stat(path + "/localhost-key.pem")
+
+	if os.IsNotExist(certExists) || os.IsNotExist(keyExists) {
+		// Run the mkcert command to generate the certificate files
+		cmd := exec.Command("mkcert", "localhost")
+		cmd.Dir = path
+		err := cmd.Run()
+		if err != nil {
+		  return err
+		}
+	}
+
+	return nil
+}
+
+func getConfigFilePath() string {
+	return filepath.Join(getRootPath(), "config.yaml")
+}
+
+func Update(newConfig Config) {
+	data, err := yaml.Marshal(&newConfig)
+
+	if err != nil {
+		log.Println("Error converting config to yaml")
+		log.Fatal(err)
+	}
+
+	err = os.WriteFile(getConfigFilePath(), data, 0644)
+
+	if err != nil {
+		log.Println("Error writing file")
+		log.Fatal(err)
+	}
+}
+
+func Path() string {
+	return getConfigFilePath()
+}
+
+func Initialize(_isTestMode bool) {
+	isTestMode = _isTestMode
+
+	if isTestMode {
+		err := os.RemoveAll(getRootPath())
+		if err != nil {
+			log.Fatalf("Failed to delete config test directory: %s", err)
+		}	
+	}
+
+	// Create root config folder if it doesn't exist
+	if _, err := os.Stat(getRootPath()); err != nil && os.IsNotExist(err) {
+		err := os.Mkdir(getRootPath(), os.ModePerm)
+		if err != nil {
+			log.Println("ERROR making config directory")
+			log.Println(err)
+		}
+	} else if err != nil {
+		fmt.Println("Error trying to get root path info: ", err)
+		panic(err)
+	}
+
+	error := initializeSSLCert()
+	if (error != nil) {
+		log.Fatal(
+			"Error initializing ssl certification:", 
+			error.Error(),
+		)
+	}
+}
+
+// Load, or create config.yaml if necessary
+func Load() Config {
+	filePath := getConfigFilePath()
+
+	// Create root config folder if it doesn't exist
+	if _, err := os.Stat(getRootPath()); err != nil && os.IsNotExist(err) {
+		err := os.Mkdir(getRootPath(), os.ModePerm)
+		if err != nil {
+			log.Println("ERROR making config directory")
+			log.Println(err)
+		}
+	} else if err != nil {
+		fmt.Println("Error trying to get root path info: ", err)
+		panic(err)
+	}
+
+	// Check if the config file exists
+	// and create it if it doesn't
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		Update(Config{
+			FolderPath: "",
+			MaxConcurrentDownloads: DefaultMaxConcurrentDownloads,
+			MaxDownloadAttempts: DefaultMaxDownloadAttempts,
+			RetryBackoffSeconds: DefaultRetryBackoffSeconds,
+		})
+	}
+
+	// NOTE this is being called on every request 
+	// Read/update from memory instead?
+	data, err := os.ReadFile(filePath)
+
+	if err != nil {
+		log.Println("Error reading config file!")
+		panic(err)
+	}
+
+	config := Config{}
+
+	err = yaml.Unmarshal(data, &config)
+
+	if err != nil {
+		log.Println("Error converting yaml to Config")
+		panic(err)
+	}
+
+	if config.MaxConcurrentDownloads < 1 {
+		config.MaxConcurrentDownloads = DefaultMaxConcurrentDownloads
+	}
+
+	if config.MaxDownloadAttempts < 1 {
+		config.MaxDownloadAttempts = DefaultMaxDownloadAttempts
+	}
+
+	if config.RetryBackoffSeconds < 1 {
+		config.RetryBackoffSeconds = DefaultRetryBackoffSeconds
+	}
+
+	return config
 }
\ No newline at end of file
@@ -1,50 +1,112 @@
 package downloadManager
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"os/exec"
 	"sort"
+	"sync"
 	"time"
+	"vidviewer/config"
+	"vidviewer/downloader"
+	customErrors "vidviewer/errors"
 	"vidviewer/models"
 	"vidviewer/repository"
 	ws "vidviewer/websocket"
+	"vidviewer/ytdlp"
 )
 
 type Download struct {
-  Command *exec.Cmd
+  Request downloader.Request
   TimeStarted int64 
   TimeCompleted int64
   IsCancelled bool
   Video models.Video
   Progress uint
   Speed string
-  Cancel chan bool
-  Pause chan bool
+  SpeedBytes float64 // bytes per second
+  Eta int64 // seconds, 0 if unknown
+  Phase string // downloading, recording, merging, extracting_thumbnail or finalizing
+  Elapsed int64 // seconds, set for live recordings
+  IsStopped bool // live recording stopped from the API, completes with the recorded file
+  FragmentIndex int
+  FragmentCount int
   IsComplete bool
   IsPaused bool
+  IsQueued bool
   IsError bool
   ErrorMsg string
+  ErrorReason string // see the reasons in vidviewer/errors
+  Attempts int
+  NextRetry int64
+  BytesDownloaded int64
+  TotalBytes int64
+  Record models.Download // row in the downloads history table
+  recordMutex sync.Mutex
+  onComplete func()
+  onError func(err error)
+  // Called by Shutdown for downloads Initialize does not resume 
+  // (eg. re-downloads of complete videos), they are cancelled
+  OnShutdown func()
+  stop context.CancelFunc // stops the running downloader
+  isRunning bool
+  appliedRateLimit int64 // rate limit of the running downloader
+  restarting bool // stopped to be started again with a new rate limit
 }
 
 type DownloadJSON struct {
+  DownloadID    int64 `json:"download_id"`
+  Status        string `json:"status"`
   TimeStarted   int64 `json:"time_started"`
   TimeCompleted int `json:"time_completed"`
   IsCancelled   bool `json:"is_cancelled"`
   IsComplete bool `json:"is_complete"`
   IsError    bool `json:"is_error"`
+  Error      string `json:"error"`
+  ErrorReason string `json:"error_reason"`
   IsPaused   bool `json:"is_paused"`
+  IsQueued   bool `json:"is_queued"`
+  QueuePosition int `json:"queue_position"`
   Title      string `json:"title"`
   URL        string `json:"url"`
   VideoID    int64 `json:"video_id"`
   Progress   uint `json:"progress"`
   Speed      string `json:"speed"`
+  SpeedBytes float64 `json:"speed_bytes"`
+  Eta        int64 `json:"eta"`
+  Phase      string `json:"phase"`
+  IsLive     bool `json:"is_live"`
+  Elapsed    int64 `json:"elapsed"`
+  BytesDownloaded int64 `json:"bytes_downloaded"`
+  TotalBytes int64 `json:"total_bytes"`
+  FragmentIndex int `json:"fragment_index"`
+  FragmentCount int `json:"fragment_count"`
+  Attempts   int `json:"attempts"`
+  MaxAttempts int `json:"max_attempts"`
+  NextRetry  int64 `json:"next_retry"`
 }
 
 type DownloadManager struct {
   IsInitialized bool;
   Downloads map[string]*Download;
+  MaxConcurrentDownloads int
+  MaxAttempts int
+  RetryBackoff time.Duration
+  // Downloads are dispatched to the first downloader that supports the url
+  Downloaders []downloader.Downloader
+  RateLimit int64 // bytes per second shared by all downloads, 0 is unlimited
+  rateLimitSchedule []config.RateLimitWindow
+  currentRateLimit int64 // RateLimit or the limit of the current schedule window
+  // Starts a download again that was interrupted by a shutdown, the 
+  // download is loaded by the handlers (see handlers.LoadVideoWithYtdlp)
+  Resume func(video models.Video) error
+  repositories *repository.Repositories
+  queue []string // keys of downloads waiting for a free slot (FIFO)
+  running int
+  shuttingDown bool // no downloads are started once set
+  mutex sync.Mutex
+  initMutex sync.Mutex // only one Initialize loads and resumes the downloads
 }
 
 var statuses map[string]DownloadJSON
@@ -52,29 +114,80 @@ var statuses map[string]DownloadJSON
 func NewDownloadManager() *DownloadManager {
   return &DownloadManager{
 		Downloads: make(map[string]*Download),
+		MaxConcurrentDownloads: config.DefaultMaxConcurrentDownloads,
+		MaxAttempts: config.DefaultMaxDownloadAttempts,
+		RetryBackoff: config.DefaultRetryBackoffSeconds * time.Second,
+		Downloaders: []downloader.Downloader{
+			downloader.NewHTTPDownloader(),
+			downloader.NewYtdlpDownloader(),
+		},
 	}
 }
 
-func (d *Download) OnStartDownload(cmd *exec.Cmd) {
-  d.TimeStarted = time.Now().Unix()
-  for {
-    select {
-    case <- d.Cancel:
-      if err := cmd.Process.Kill(); err != nil {
-        log.Printf("error during video cancel - failed to kill ytdlp process: %v", err)
-      }
-      return
-    }
-  }
-}
-
+// Must be called with dm.mutex locked.
 func (d *Download) OnCancel() {
-	close(d.Cancel)
   d.IsCancelled = true
+  d.stopDownloader()
 }
 
+// The partial file is kept in the temp folder,
+// the download continues from it when resumed.
+// Must be called with dm.mutex locked.
 func (d *Download) OnPause() {
-	d.Pause <- true
+  d.IsPaused = true
+  d.stopDownloader()
+}
+
+func (d *Download) stopDownloader() {
+  if d.stop != nil {
+    d.stop()
+  }
+}
+
+// Must be called with dm.mutex locked.
+func (d *Download) onProgress(progress downloader.Progress) {
+  d.Phase = progress.Phase
+  d.Progress = progress.Percent
+
+  // Postprocessing (merging etc.) keeps the downloaded size
+  if progress.Phase != ytdlp.PhaseDownloading {
+    d.Speed = ""
+    d.SpeedBytes = 0
+    d.Eta = 0
+    return
+  }
+
+  // Live streams have no total size, only what was recorded so far
+  if d.Request.Live != "" {
+    d.Phase = ytdlp.PhaseRecording
+    d.Elapsed = int64(progress.Elapsed)
+  }
+
+  d.Speed = downloader.FormatSpeed(progress.Speed)
+  d.SpeedBytes = progress.Speed
+  d.Eta = progress.Eta
+  d.FragmentIndex = progress.FragmentIndex
+  d.FragmentCount = progress.FragmentCount
+
+  if progress.DownloadedBytes > 0 {
+    d.BytesDownloaded = progress.DownloadedBytes
+  }
+
+  if progress.TotalBytes > 0 {
+    d.TotalBytes = progress.TotalBytes
+  }
+}
+
+// Sets the error message and its reason, errors that
+// were not classified have the reason "unknown"
+func (d *Download) SetError(err error) {
+  d.ErrorMsg = err.Error()
+  d.ErrorReason = customErrors.ReasonUnknown
+
+  var downloadErr *customErrors.Error
+  if errors.As(err, &downloadErr) && downloadErr.Reason != "" {
+    d.ErrorReason = downloadErr.Reason
+  }
 }
 
 func (d *Download) OnComplete() {
@@ -82,8 +195,111 @@ func (d *Download) OnComplete() {
   d.TimeCompleted = time.Now().Unix()
 }
 
-func (dm *DownloadManager) Initialize(repo repository.VideoRepository) {
-  videos, err := repo.GetIncompleteDownloads()
+func (d *Download) Status() string {
+  switch {
+  case d.IsCancelled:
+    return models.DownloadStatusCancelled
+  case d.IsComplete:
+    return models.DownloadStatusComplete
+  case d.IsError:
+    return models.DownloadStatusFailed
+  case d.IsPaused:
+    return models.DownloadStatusPaused
+  case d.IsQueued:
+    return models.DownloadStatusQueued
+  case d.NextRetry != 0:
+    return models.DownloadStatusRetrying
+  default:
+    return models.DownloadStatusDownloading
+  }
+}
+
+// Writes the current state of the download to the downloads table
+func (dm *DownloadManager) saveRecord(d *Download) {
+  dm.updateRecord(d)
+  dm.writeRecord(d)
+}
+
+// Copies the current state of the download to its record, 
+// to be written by writeRecord once dm.mutex is unlocked
+func (dm *DownloadManager) updateRecord(d *Download) {
+  d.recordMutex.Lock()
+  defer d.recordMutex.Unlock()
+
+  record := &d.Record
+  now := time.Now().Unix()
+
+  record.VideoID = d.Video.ID
+  record.Url = d.Video.Url
+  record.Title = d.Video.Title
+  record.Format = d.Video.VideoFormat.String
+  record.Status = d.Status()
+  record.Error = d.ErrorMsg
+  record.ErrorReason = d.ErrorReason
+
+  if d.BytesDownloaded > 0 {
+    record.BytesDownloaded = d.BytesDownloaded
+  }
+
+  if d.TotalBytes > 0 {
+    record.TotalBytes = d.TotalBytes
+  }
+
+  if record.TimeStarted == 0 && record.Status == models.DownloadStatusDownloading {
+    record.TimeStarted = now
+  }
+
+  if record.IsFinished() && record.TimeCompleted == 0 {
+    record.TimeCompleted = now
+
+    if record.TimeStarted != 0 && record.BytesDownloaded > 0 {
+      elapsed := record.TimeCompleted - record.TimeStarted
+      if elapsed < 1 {
+        elapsed = 1
+      }
+      record.AverageSpeed = float64(record.BytesDownloaded) / float64(elapsed)
+    }
+  }
+}
+
+// Writes the record to the downloads table, 
+// must be called without dm.mutex locked
+func (dm *DownloadManager) writeRecord(d *Download) {
+  if dm.repositories == nil {
+    return
+  }
+
+  d.recordMutex.Lock()
+  defer d.recordMutex.Unlock()
+
+  repo := dm.repositories.DownloadRepo
+  record := &d.Record
+
+  var err error
+  if record.ID == 0 {
+    record.ID, err = repo.Create(*record)
+  } else {
+    err = repo.Update(*record)
+  }
+
+  if err != nil {
+    log.Println("Error saving download record:", err)
+  }
+}
+
+// Loads the incomplete downloads. Downloads that were not paused by 
+// the user (interrupted by a shutdown or a crash) are started again.
+// Does nothing once it succeeded.
+func (dm *DownloadManager) Initialize(repositories *repository.Repositories) {
+  dm.initMutex.Lock()
+  defer dm.initMutex.Unlock()
+
+  if dm.IsInitialized {
+    return
+  }
+
+  dm.repositories = repositories
+  videos, err := repositories.VideoRepo.GetIncompleteDownloads()
 
   if (err == nil) {
     for _, video := range videos {
@@ -92,6 +308,76 @@ func (dm *DownloadManager) Initialize(repo repository.VideoRepository) {
     }
     dm.IsInitialized = true
     go dm.startStatusUpdates()
+    go dm.startRateLimitUpdates()
+
+    for _, video := range videos {
+      if video.DownloadPaused || dm.Resume == nil {
+        continue
+      }
+
+      log.Println("resuming interrupted download: ", video.Title)
+
+      if err := dm.Resume(*video); err != nil {
+        log.Println("Error resuming download:", err)
+      }
+    }
+  }
+}
+
+// Stops the running downloads before the server exits. yt-dlp is
+// interrupted so it stops its ffmpeg processes and keeps the partial 
+// files, the downloads are started again by Initialize on the next 
+// start. Live recordings are stopped and saved. Waits until the 
+// downloads stopped or the timeout passed.
+func (dm *DownloadManager) Shutdown(timeout time.Duration) {
+  dm.mutex.Lock()
+  dm.shuttingDown = true
+  dm.queue = nil
+
+  downloads := []*Download{}
+
+  for _, d := range dm.Downloads {
+    if d.IsCancelled || d.IsComplete || d.IsError || d.IsPaused {
+      continue
+    }
+
+    downloads = append(downloads, d)
+    d.IsQueued = false
+
+    if d.Request.Live != "" && d.isRunning {
+      d.IsStopped = true
+      d.stopDownloader()
+    } else {
+      d.OnPause()
+    }
+  }
+  dm.mutex.Unlock()
+
+  deadline := time.Now().Add(timeout)
+
+  for time.Now().Before(deadline) {
+    dm.mutex.Lock()
+    running := dm.running
+    dm.mutex.Unlock()
+
+    if running == 0 {
+      break
+    }
+
+    time.Sleep(100 * time.Millisecond)
+  }
+
+  // Keep the progress for the next start
+  for _, d := range downloads {
+    if d.OnShutdown != nil {
+      dm.mutex.Lock()
+      d.IsCancelled = true
+      dm.mutex.Unlock()
+
+      d.OnShutdown()
+    }
+
+    dm.saveRecord(d)
   }
 }
 
@@ -103,25 +389,50 @@ func (dm *DownloadManager) startStatusUpdates() {
 
   for range ticker.C {
     isUpdate = false
-    
+
+    dm.mutex.Lock()
     for key, d := range dm.Downloads {
       if !d.IsCancelled && !d.IsComplete {
         isUpdate = true 
       }
 
+      // yt-dlp only reports the elapsed time with some downloaders
+      if d.Request.Live != "" && d.isRunning && d.Elapsed == 0 {
+        d.Elapsed = time.Now().Unix() - d.TimeStarted
+      }
+
       statuses[key] = DownloadJSON {
+        DownloadID: d.Record.ID,
+        Status: d.Status(),
         TimeStarted: d.TimeStarted,
         VideoID: d.Video.ID,
         IsComplete: d.IsComplete,
         IsCancelled: d.IsCancelled,
         IsError: d.IsError,
+        Error: d.ErrorMsg,
+        ErrorReason: d.ErrorReason,
         IsPaused: d.IsPaused,
+        IsQueued: d.IsQueued,
+        QueuePosition: dm.queuePosition(key),
         URL: d.Video.Url,
         Title: d.Video.Title,
         Progress: d.Progress,
         Speed: d.Speed,
+        SpeedBytes: d.SpeedBytes,
+        Eta: d.Eta,
+        Phase: d.Phase,
+        IsLive: d.Request.Live != "",
+        Elapsed: d.Elapsed,
+        BytesDownloaded: d.BytesDownloaded,
+        TotalBytes: d.TotalBytes,
+        FragmentIndex: d.FragmentIndex,
+        FragmentCount: d.FragmentCount,
+        Attempts: d.Attempts,
+        MaxAttempts: dm.MaxAttempts,
+        NextRetry: d.NextRetry,
       } 
     }
+    dm.mutex.Unlock()
 
     if prevIsUpdate {
       // Create array(slice) of download statuses, sort by time started
@@ -148,6 +459,326 @@ func (dm *DownloadManager) startStatusUpdates() {
   }
 }
 
+// Applies the download settings of the config
+func (dm *DownloadManager) ApplyConfig(c config.Config) {
+  dm.SetMaxConcurrentDownloads(c.MaxConcurrentDownloads)
+  dm.SetRetryPolicy(c.MaxDownloadAttempts, time.Duration(c.RetryBackoffSeconds) * time.Second)
+  dm.SetRateLimit(c.RateLimit, c.RateLimitSchedule)
+}
+
+// Updates the number of downloads allowed to run at the same time,
+// starting queued downloads if the limit was raised
+func (dm *DownloadManager) SetMaxConcurrentDownloads(max int) {
+  if max < 1 {
+    max = 1
+  }
+
+  dm.mutex.Lock()
+  defer dm.mutex.Unlock()
+
+  if dm.MaxConcurrentDownloads != max {
+    dm.MaxConcurrentDownloads = max
+    dm.startQueuedDownloads()
+  }
+}
+
+// Adds the download to the back of the queue, d.Request is downloaded 
+// once a download slot is free. onComplete or onError (the downloader 
+// failed or was stopped) are called when the downloader returns, 
+// the slot is released after they return.
+func (dm *DownloadManager) Enqueue(d *Download, onComplete func(), onError func(err error)) {
+  d.onComplete = onComplete
+  d.onError = onError
+  dm.enqueue(d)
+}
+
+func (dm *DownloadManager) enqueue(d *Download) {
+  dm.mutex.Lock()
+
+  // Paused or cancelled while waiting to be retried
+  if d.IsCancelled || d.IsPaused {
+    dm.mutex.Unlock()
+    return
+  }
+
+  key := fmt.Sprint(d.Video.ID)
+
+  d.IsQueued = true
+  dm.queue = append(dm.queue, key)
+  dm.updateRecord(d)
+  dm.startQueuedDownloads()
+  dm.mutex.Unlock()
+
+  dm.writeRecord(d)
+}
+
+// Starts queued downloads until there are no free slots.
+// Must be called with dm.mutex locked.
+func (dm *DownloadManager) startQueuedDownloads() {
+  for !dm.shuttingDown && dm.running < dm.MaxConcurrentDownloads && len(dm.queue) > 0 {
+    key := dm.queue[0]
+    dm.queue = dm.queue[1:]
+
+    d := dm.Downloads[key]
+    if d == nil || !d.IsQueued {
+      continue
+    }
+
+    d.IsQueued = false
+    dm.running++
+
+    go dm.runDownload(d)
+  }
+}
+
+func (dm *DownloadManager) runDownload(d *Download) {
+  dm.mutex.Lock()
+  d.Attempts++
+  d.NextRetry = 0
+  dm.updateRecord(d)
+  dm.mutex.Unlock()
+
+  dm.writeRecord(d)
+
+  err := dm.download(d)
+
+  // Stopped to change the rate limit, start it again in the same slot
+  for err != nil && d.restarting && !d.IsCancelled {
+    dm.mutex.Lock()
+    d.restarting = false
+    d.IsPaused = false
+    dm.mutex.Unlock()
+
+    err = dm.download(d)
+  }
+
+  dm.mutex.Lock()
+  d.restarting = false
+  // The recording was finalized when yt-dlp was interrupted
+  if err != nil && d.IsStopped && !d.IsCancelled {
+    err = nil
+  }
+  dm.mutex.Unlock()
+
+  if err != nil {
+    d.onError(err)
+  } else {
+    d.onComplete()
+  }
+
+  dm.saveRecord(d)
+
+  dm.mutex.Lock()
+  defer dm.mutex.Unlock()
+
+  dm.running--
+  dm.startQueuedDownloads()
+}
+
+// Runs the downloader for the download's request with the current rate limit
+func (dm *DownloadManager) download(d *Download) error {
+  ctx, stop := context.WithCancel(context.Background())
+  defer stop()
+
+  dm.mutex.Lock()
+  request := d.Request
+  request.RateLimit = dm.downloadRateLimit(d)
+  d.appliedRateLimit = request.RateLimit
+  d.stop = stop
+  d.isRunning = true
+  d.TimeStarted = time.Now().Unix()
+
+  // Paused or cancelled while waiting in the queue
+  if d.IsPaused || d.IsCancelled {
+    stop()
+  }
+  dm.mutex.Unlock()
+
+  err := dm.downloaderFor(request.Url).Download(ctx, request, func(progress downloader.Progress) {
+    dm.mutex.Lock()
+    defer dm.mutex.Unlock()
+    d.onProgress(progress)
+  })
+
+  dm.mutex.Lock()
+  d.stop = nil
+  d.isRunning = false
+  dm.mutex.Unlock()
+
+  return err
+}
+
+func (dm *DownloadManager) downloaderFor(url string) downloader.Downloader {
+  for _, dl := range dm.Downloaders {
+    if dl.Supports(url) {
+      return dl
+    }
+  }
+  return downloader.NewYtdlpDownloader()
+}
+
+func (dm *DownloadManager) startRateLimitUpdates() {
+  ticker := time.NewTicker(1 * time.Minute)
+
+  for range ticker.C {
+    dm.updateRateLimit()
+  }
+}
+
+// Updates the global rate limit and its schedule
+func (dm *DownloadManager) SetRateLimit(rateLimit int64, schedule []config.RateLimitWindow) {
+  dm.mutex.Lock()
+  dm.RateLimit = rateLimit
+  dm.rateLimitSchedule = schedule
+  dm.mutex.Unlock()
+
+  dm.updateRateLimit()
+}
+
+// Restarts the running downloads with the new rate limit when
+// the global rate limit changes, eg. when a schedule window starts
+func (dm *DownloadManager) updateRateLimit() {
+  dm.mutex.Lock()
+  defer dm.mutex.Unlock()
+
+  rateLimit := config.CurrentRateLimit(dm.RateLimit, dm.rateLimitSchedule, time.Now())
+
+  if rateLimit == dm.currentRateLimit {
+    return
+  }
+
+  log.Printf("rate limit changed from %d to %d bytes/s", dm.currentRateLimit, rateLimit)
+  dm.currentRateLimit = rateLimit
+
+  for _, d := range dm.Downloads {
+    if dm.downloadRateLimit(d) != d.appliedRateLimit {
+      dm.restartDownload(d)
+    }
+  }
+}
+
+// The download's own rate limit, or its share of the global rate limit.
+// Must be called with dm.mutex locked.
+func (dm *DownloadManager) downloadRateLimit(d *Download) int64 {
+  if d.Video.RateLimit > 0 {
+    return d.Video.RateLimit
+  }
+
+  if dm.currentRateLimit <= 0 {
+    return 0
+  }
+
+  // Split between the download slots so the total stays under the limit
+  share := dm.currentRateLimit / int64(dm.MaxConcurrentDownloads)
+
+  // 0 would be unlimited, for limits lower than the number of slots
+  if share < 1 {
+    share = 1
+  }
+
+  return share
+}
+
+// Stops the downloader of a running download, runDownload starts 
+// it again (continuing the partial file) with the current rate limit.
+// Must be called with dm.mutex locked.
+func (dm *DownloadManager) restartDownload(d *Download) {
+  // Restarting a live recording would end it
+  if !d.isRunning || d.restarting || d.IsPaused || d.IsCancelled || d.IsComplete || d.Request.Live != "" {
+    return
+  }
+
+  log.Printf("restarting download %d with the new rate limit", d.Video.ID)
+
+  d.restarting = true
+  d.OnPause()
+}
+
+// Updates the number of attempts made for a download 
+// and the delay before the first retry
+func (dm *DownloadManager) SetRetryPolicy(maxAttempts int, backoff time.Duration) {
+  dm.mutex.Lock()
+  defer dm.mutex.Unlock()
+
+  dm.MaxAttempts = maxAttempts
+  dm.RetryBackoff = backoff
+}
+
+// Schedules the download to be queued again after a delay that 
+// doubles with every failed attempt. Downloaded data is kept so 
+// yt-dlp continues from the partial file.
+// Returns false if there are no attempts left, or the error 
+// is not transient (eg. the video is private or removed).
+func (dm *DownloadManager) RetryDownload(d *Download, err error) bool {
+  dm.mutex.Lock()
+
+  d.SetError(err)
+
+  if d.Attempts >= dm.MaxAttempts || !customErrors.IsRetryable(d.ErrorReason) {
+    dm.mutex.Unlock()
+    return false
+  }
+
+  delay := dm.RetryBackoff * time.Duration(1 << (d.Attempts - 1))
+  d.NextRetry = time.Now().Add(delay).Unix()
+
+  log.Printf("download %d failed (attempt %d of %d), retrying in %s: %v", d.Video.ID, d.Attempts, dm.MaxAttempts, delay, err)
+
+  // enqueue skips the download if it was paused or cancelled in the meantime
+  time.AfterFunc(delay, func() {
+    dm.enqueue(d)
+  })
+
+  dm.updateRecord(d)
+  dm.mutex.Unlock()
+
+  dm.writeRecord(d)
+
+  return true
+}
+
+// Marks the download as failed for good, called
+// once RetryDownload returns false
+func (dm *DownloadManager) FailDownload(d *Download, err error) {
+  dm.mutex.Lock()
+  defer dm.mutex.Unlock()
+
+  d.IsError = true
+  d.IsComplete = false
+  d.SetError(err)
+  d.TimeCompleted = time.Now().Unix()
+}
+
+// Marks the download as complete once the video is saved
+func (dm *DownloadManager) CompleteDownload(d *Download) {
+  dm.mutex.Lock()
+  defer dm.mutex.Unlock()
+
+  d.OnComplete()
+}
+
+// Removes the download from the queue if it is waiting for a slot.
+// Must be called with dm.mutex locked.
+func (dm *DownloadManager) removeFromQueue(key string) {
+  for i, k := range dm.queue {
+    if k == key {
+      dm.queue = append(dm.queue[:i], dm.queue[i+1:]...)
+      break
+    }
+  }
+}
+
+// 1 based position of the download in the queue, 0 if not queued.
+// Must be called with dm.mutex locked.
+func (dm *DownloadManager) queuePosition(key string) int {
+  for i, k := range dm.queue {
+    if k == key {
+      return i + 1
+    }
+  }
+  return 0
+}
+
 // Send cancel event to download channel
 func (dm *DownloadManager) CancelDownload(key string) (*Download, error){
   d := dm.GetDownload(key)
@@ -155,25 +786,128 @@ func (dm *DownloadManager) CancelDownload(key string) (*Download, error){
     log.Println("Error while trying to cancel download, download does not exist:", key)
     return nil, errors.New("download not found") 
   } else {
+    dm.mutex.Lock()
+    if d.IsQueued {
+      d.IsQueued = false
+      dm.removeFromQueue(key)
+    }
     d.OnCancel()
+    dm.mutex.Unlock()
+
+    dm.saveRecord(d)
     return d, nil
   }
 }
 
-func (dm *DownloadManager) OnResumeDownload(key string) error {
+// Stops the download process, or removes it from the queue.
+// Downloaded data is kept so the download can be resumed.
+func (dm *DownloadManager) PauseDownload(key string) (*Download, error) {
   d := dm.GetDownload(key)
   if d == nil {
-    return errors.New("download not found")
-  } else {
-    d.IsPaused = false
-    return nil
+    return nil, errors.New("download not found")
+  }
+
+  if d.IsCancelled || d.IsComplete {
+    return nil, errors.New("download is not in progress")
+  }
+
+  if d.IsPaused {
+    return d, nil
+  }
+
+  // Missed parts of a stream can't be downloaded later
+  if d.Request.Live != "" {
+    return nil, errors.New("live recordings can only be stopped")
+  }
+
+  dm.mutex.Lock()
+  if d.IsQueued {
+    d.IsQueued = false
+    dm.removeFromQueue(key)
+  }
+  d.OnPause()
+  dm.mutex.Unlock()
+
+  dm.saveRecord(d)
+  return d, nil
+}
+
+// Stops a live recording. yt-dlp is interrupted so it finalizes 
+// the file, the download then completes with what was recorded.
+func (dm *DownloadManager) StopRecording(key string) (*Download, error) {
+  d := dm.GetDownload(key)
+  if d == nil {
+    return nil, errors.New("download not found")
+  }
+
+  dm.mutex.Lock()
+  defer dm.mutex.Unlock()
+
+  if d.Request.Live == "" {
+    return nil, errors.New("download is not a live recording")
+  }
+
+  if !d.isRunning || d.IsCancelled || d.IsComplete {
+    return nil, errors.New("recording is not running")
   }
+
+  d.IsStopped = true
+  d.stopDownloader()
+
+  return d, nil
+}
+
+// Puts a paused download back in the queue.
+// Returns false if the download was not started by this 
+// server process (eg. paused before a restart) and has to be
+// loaded again.
+func (dm *DownloadManager) OnResumeDownload(key string) (bool, error) {
+  d := dm.GetDownload(key)
+  if d == nil {
+    return false, errors.New("download not found")
+  }
+
+  if !d.IsPaused {
+    return true, nil
+  }
+
+  if d.onComplete == nil {
+    return false, nil
+  }
+
+  d.IsPaused = false
+  d.Attempts = 0
+  dm.enqueue(d)
+  return true, nil
 }
 
 func (dm *DownloadManager) GetDownload(key string) *Download{
+  dm.mutex.Lock()
+  defer dm.mutex.Unlock()
+
   return dm.Downloads[key]
 }
 
+// Whether the download is queued, running, paused or waiting to be
+// retried. Starting it again would replace it while yt-dlp still runs.
+func (dm *DownloadManager) IsDownloadActive(key string) bool {
+  dm.mutex.Lock()
+  defer dm.mutex.Unlock()
+
+  d, exists := dm.Downloads[key]
+
+  if !exists {
+    return false
+  }
+
+  switch d.Status() {
+  case models.DownloadStatusComplete, models.DownloadStatusCancelled, models.DownloadStatusFailed:
+    return false
+  default:
+    return true
+  }
+}
+
 func (dm *DownloadManager) AddPreviousDownload(video *models.Video) {
   key := fmt.Sprint(video.ID)
 
@@ -186,11 +920,26 @@ func (dm *DownloadManager) AddPreviousDownload(video *models.Video) {
 		IsPaused: true,
 		Progress: 0,
 		Speed: "",
-		Cancel: make(chan bool),
-		Pause: make(chan bool),
 	}
 
+	// Continue the download's history entry
+	if dm.repositories != nil {
+		record, err := dm.repositories.DownloadRepo.GetUnfinished(video.ID)
+		if err != nil {
+			log.Println("Error getting download record:", err)
+		} else if record != nil {
+			d.Record = *record
+			d.BytesDownloaded = record.BytesDownloaded
+			d.TotalBytes = record.TotalBytes
+			d.Progress = record.Percent()
+		}
+	}
+
+	dm.mutex.Lock()
 	dm.Downloads[key] = d
+	dm.mutex.Unlock()
+
+	dm.saveRecord(d)
 }
 
 func (dm *DownloadManager) AddNewDownload(video models.Video) (*Download, error) {
@@ -205,11 +954,18 @@ func (dm *DownloadManager) AddNewDownload(video models.Video) (*Download, error)
 		IsPaused: false,
 		Progress: 0,
 		Speed: "",
-		Cancel: make(chan bool),
-		Pause: make(chan bool),
 	}
 
+	dm.mutex.Lock()
+	// Keep the history entry when a download is started again
+	if previous, exists := dm.Downloads[key]; exists && !previous.Record.IsFinished() {
+		d.Record = previous.Record
+		d.BytesDownloaded = previous.BytesDownloaded
+		d.TotalBytes = previous.TotalBytes
+		d.Progress = previous.Progress
+	}
 	dm.Downloads[key] = d
+	dm.mutex.Unlock()
 
 	return d, nil
 }
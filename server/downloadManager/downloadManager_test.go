@@ -0,0 +1,131 @@
+package downloadManager
+
+import (
+	"errors"
+	"testing"
+	"time"
+	customErrors "vidviewer/errors"
+	"vidviewer/models"
+)
+
+func newTestDownload(dm *DownloadManager) *Download {
+	d := &Download{Video: models.Video{ID: 1}, Attempts: 1}
+	dm.Downloads["1"] = d
+	return d
+}
+
+func TestRetryDownload(t *testing.T) {
+	dm := NewDownloadManager()
+	dm.MaxAttempts = 3
+	dm.RetryBackoff = time.Millisecond
+	// Queued downloads are not started
+	dm.shuttingDown = true
+
+	d := newTestDownload(dm)
+
+	if dm.RetryDownload(d, customErrors.VideoPrivateError()) {
+		t.Errorf("Expected a private video not to be retried")
+	}
+
+	if dm.RetryDownload(d, customErrors.FormatUnavailableError()) {
+		t.Errorf("Expected a missing format not to be retried")
+	}
+
+	if !dm.RetryDownload(d, customErrors.NetworkError()) {
+		t.Fatalf("Expected a network error to be retried")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	dm.mutex.Lock()
+	isQueued := d.IsQueued
+	dm.mutex.Unlock()
+
+	if !isQueued {
+		t.Errorf("Expected the download to be queued again")
+	}
+
+	d.Attempts = 3
+
+	if dm.RetryDownload(d, errors.New("something went wrong")) {
+		t.Errorf("Expected no retry once there are no attempts left")
+	}
+}
+
+func TestRetryDownloadPausedWhileWaiting(t *testing.T) {
+	dm := NewDownloadManager()
+	dm.MaxAttempts = 3
+	dm.RetryBackoff = 20 * time.Millisecond
+	dm.shuttingDown = true
+
+	d := newTestDownload(dm)
+
+	if !dm.RetryDownload(d, customErrors.NetworkError()) {
+		t.Fatalf("Expected a network error to be retried")
+	}
+
+	dm.mutex.Lock()
+	d.OnPause()
+	dm.mutex.Unlock()
+
+	time.Sleep(60 * time.Millisecond)
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	if d.IsQueued || len(dm.queue) > 0 {
+		t.Errorf("Expected the paused download not to be queued")
+	}
+}
+
+func TestIsDownloadActive(t *testing.T) {
+	dm := NewDownloadManager()
+
+	if dm.IsDownloadActive("1") {
+		t.Errorf("Expected an unknown download not to be active")
+	}
+
+	d := newTestDownload(dm)
+	d.IsQueued = true
+
+	if !dm.IsDownloadActive("1") {
+		t.Errorf("Expected a queued download to be active")
+	}
+
+	d.IsQueued = false
+	d.IsError = true
+
+	if dm.IsDownloadActive("1") {
+		t.Errorf("Expected a failed download not to be active")
+	}
+}
+
+func TestDownloadRateLimit(t *testing.T) {
+	dm := NewDownloadManager()
+	dm.MaxConcurrentDownloads = 4
+
+	d := newTestDownload(dm)
+
+	if limit := dm.downloadRateLimit(d); limit != 0 {
+		t.Errorf("Expected no limit, got %d", limit)
+	}
+
+	dm.currentRateLimit = 1000
+
+	if limit := dm.downloadRateLimit(d); limit != 250 {
+		t.Errorf("Expected the limit to be split between the slots, got %d", limit)
+	}
+
+	// Lower than the number of slots
+	dm.currentRateLimit = 3
+
+	if limit := dm.downloadRateLimit(d); limit != 1 {
+		t.Errorf("Expected the smallest limit, got %d", limit)
+	}
+
+	d.Video.RateLimit = 500
+
+	if limit := dm.downloadRateLimit(d); limit != 500 {
+		t.Errorf("Expected the download's own limit, got %d", limit)
+	}
+}
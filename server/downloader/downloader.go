@@ -0,0 +1,91 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"vidviewer/ytdlp"
+)
+
+// Progress event of a download, the HTTP downloader reports
+// the same fields yt-dlp does (without fragments)
+type Progress = ytdlp.Progress
+
+type ProgressFunc func(progress Progress)
+
+type Request struct {
+	Url        string
+	Format     string // yt-dlp format id, empty for the best format
+	FileFormat string // extension of the downloaded file, eg. "mp4" or "m4a"
+	FilePath   string // where the file is written
+	RateLimit  int64  // bytes per second, 0 is unlimited
+	// yt-dlp only, "mark" or "remove" (see ytdlp.SetSponsorBlock)
+	SponsorBlock string
+	// yt-dlp only, records a live stream, "now" or "start" (see ytdlp.IsLiveMode).
+	// Cancelling the download stops the recording and keeps the file.
+	Live string
+}
+
+type Downloader interface {
+	// Whether the downloader can download the url
+	Supports(url string) bool
+	// Downloads the file, blocking until it is complete.
+	// Partial downloads are continued. Cancelling ctx stops
+	// the download and keeps the partial file.
+	Download(ctx context.Context, request Request, onProgress ProgressFunc) error
+}
+
+// Extensions of media files that are downloaded over HTTP without yt-dlp
+var directMediaExtensions = []string{".mp4", ".webm"}
+
+// Whether the url links directly to a media file
+func IsDirectMediaUrl(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	ext := strings.ToLower(path.Ext(u.Path))
+
+	for _, directExt := range directMediaExtensions {
+		if ext == directExt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The file format (extension without the dot) and
+// the name of the file a direct media url links to
+func ParseDirectMediaUrl(rawUrl string) (string, string) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", ""
+	}
+
+	base := path.Base(u.Path)
+	ext := path.Ext(base)
+
+	name, err := url.PathUnescape(strings.TrimSuffix(base, ext))
+	if err != nil {
+		name = strings.TrimSuffix(base, ext)
+	}
+
+	return strings.ToLower(strings.TrimPrefix(ext, ".")), name
+}
+
+// Formats bytes per second like yt-dlp, eg. "1.50MiB/s"
+func FormatSpeed(bytesPerSecond float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	unit := 0
+
+	for bytesPerSecond >= 1024 && unit < len(units)-1 {
+		bytesPerSecond /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.2f%s/s", bytesPerSecond, units[unit])
+}
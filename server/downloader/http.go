@@ -0,0 +1,192 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+	customErrors "vidviewer/errors"
+	"vidviewer/ytdlp"
+)
+
+// How often progress is reported
+const progressInterval = 500 * time.Millisecond
+
+// Downloads direct links to media files.
+// Data is written to FilePath + ".part" and continued
+// with a Range request if the download is stopped.
+type HTTPDownloader struct {
+	Client *http.Client
+}
+
+func NewHTTPDownloader() *HTTPDownloader {
+	return &HTTPDownloader{Client: http.DefaultClient}
+}
+
+func (h *HTTPDownloader) Supports(url string) bool {
+	return IsDirectMediaUrl(url)
+}
+
+func (h *HTTPDownloader) Download(ctx context.Context, request Request, onProgress ProgressFunc) error {
+	partPath := request.FilePath + ".part"
+
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, request.Url, nil)
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		httpRequest.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	response, err := h.Client.Do(httpRequest)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Println("Error requesting", request.Url, err)
+		return customErrors.NetworkError()
+	}
+
+	defer response.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	total := int64(-1)
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+		if response.ContentLength >= 0 {
+			total = offset + response.ContentLength
+		}
+	case http.StatusOK:
+		// The server ignored the range, start over
+		flags |= os.O_TRUNC
+		offset = 0
+		total = response.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The part file is already complete
+		if offset > 0 {
+			onProgress(Progress{Phase: ytdlp.PhaseDownloading, Percent: 100, DownloadedBytes: offset, TotalBytes: offset})
+			return os.Rename(partPath, request.FilePath)
+		}
+		return responseError(response)
+	default:
+		return responseError(response)
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	downloaded, err := copyWithProgress(ctx, file, response.Body, offset, total, request.RateLimit, onProgress)
+	closeErr := file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if total >= 0 && downloaded < total {
+		return io.ErrUnexpectedEOF
+	}
+
+	return os.Rename(partPath, request.FilePath)
+}
+
+// Classifies an unexpected response like the yt-dlp errors
+func responseError(response *http.Response) error {
+	switch response.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return customErrors.VideoRemovedError()
+	case http.StatusTooManyRequests:
+		return customErrors.RateLimitedError()
+	case http.StatusUnavailableForLegalReasons:
+		return customErrors.GeoBlockedError()
+	default:
+		return customErrors.DownloadFailedError(fmt.Sprintf("unexpected response: %s", response.Status))
+	}
+}
+
+// Copies src to dst, reporting progress and sleeping to stay under the
+// rate limit. Returns the total bytes downloaded including offset.
+func copyWithProgress(ctx context.Context, dst io.Writer, src io.Reader, offset int64, total int64, rateLimit int64, onProgress ProgressFunc) (int64, error) {
+	buffer := make([]byte, 32*1024)
+	downloaded := offset
+	started := time.Now()
+	lastReport := time.Time{}
+
+	report := func() {
+		progress := Progress{Phase: ytdlp.PhaseDownloading, DownloadedBytes: downloaded}
+
+		if elapsed := time.Since(started).Seconds(); elapsed > 0 {
+			progress.Speed = float64(downloaded-offset) / elapsed
+		}
+
+		if total > 0 {
+			progress.TotalBytes = total
+			progress.Percent = uint(downloaded * 100 / total)
+
+			if progress.Speed > 0 {
+				progress.Eta = int64(float64(total-downloaded) / progress.Speed)
+			}
+		}
+
+		onProgress(progress)
+		lastReport = time.Now()
+	}
+
+	for {
+		n, readErr := src.Read(buffer)
+
+		if n > 0 {
+			if _, err := dst.Write(buffer[:n]); err != nil {
+				return downloaded, err
+			}
+			downloaded += int64(n)
+
+			if rateLimit > 0 {
+				expected := time.Duration(float64(downloaded-offset) / float64(rateLimit) * float64(time.Second))
+				if wait := expected - time.Since(started); wait > 0 {
+					select {
+					case <-ctx.Done():
+						return downloaded, ctx.Err()
+					case <-time.After(wait):
+					}
+				}
+			}
+
+			if time.Since(lastReport) >= progressInterval {
+				report()
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+
+		if readErr != nil {
+			if ctx.Err() != nil {
+				return downloaded, ctx.Err()
+			}
+			log.Println("Error reading response:", readErr)
+			return downloaded, customErrors.NetworkError()
+		}
+	}
+
+	report()
+
+	return downloaded, nil
+}
@@ -0,0 +1,154 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	customErrors "vidviewer/errors"
+)
+
+var testContent = bytes.Repeat([]byte("0123456789"), 10000)
+
+// Serves testContent with Range support, counting the requests with a Range header
+func newTestServer(t *testing.T, rangeRequests *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			*rangeRequests++
+		}
+		http.ServeContent(w, r, "video.mp4", time.Time{}, bytes.NewReader(testContent))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPDownload(t *testing.T) {
+	rangeRequests := 0
+	server := newTestServer(t, &rangeRequests)
+	filePath := filepath.Join(t.TempDir(), "video.mp4")
+
+	var lastProgress Progress
+	err := NewHTTPDownloader().Download(context.Background(), Request{Url: server.URL + "/video.mp4", FilePath: filePath}, func(progress Progress) {
+		lastProgress = progress
+	})
+
+	if err != nil {
+		t.Fatalf("Error downloading: %s", err)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil || !bytes.Equal(content, testContent) {
+		t.Errorf("Downloaded file does not match the served content")
+	}
+
+	if lastProgress.Percent != 100 || lastProgress.DownloadedBytes != int64(len(testContent)) || lastProgress.TotalBytes != int64(len(testContent)) {
+		t.Errorf("Expected the last progress to be complete, got %+v", lastProgress)
+	}
+
+	if _, err := os.Stat(filePath + ".part"); !os.IsNotExist(err) {
+		t.Errorf("Expected the part file to be renamed")
+	}
+
+	if rangeRequests != 0 {
+		t.Errorf("Expected no range requests, got %d", rangeRequests)
+	}
+}
+
+func TestHTTPDownloadResumesPartFile(t *testing.T) {
+	rangeRequests := 0
+	server := newTestServer(t, &rangeRequests)
+	filePath := filepath.Join(t.TempDir(), "video.mp4")
+
+	err := os.WriteFile(filePath+".part", testContent[:12345], 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewHTTPDownloader().Download(context.Background(), Request{Url: server.URL + "/video.mp4", FilePath: filePath}, func(progress Progress) {})
+
+	if err != nil {
+		t.Fatalf("Error downloading: %s", err)
+	}
+
+	content, _ := os.ReadFile(filePath)
+	if !bytes.Equal(content, testContent) {
+		t.Errorf("Resumed file does not match the served content, got %d bytes", len(content))
+	}
+
+	if rangeRequests != 1 {
+		t.Errorf("Expected one range request, got %d", rangeRequests)
+	}
+}
+
+func TestHTTPDownloadCompletePartFile(t *testing.T) {
+	rangeRequests := 0
+	server := newTestServer(t, &rangeRequests)
+	filePath := filepath.Join(t.TempDir(), "video.mp4")
+
+	os.WriteFile(filePath+".part", testContent, 0644)
+
+	err := NewHTTPDownloader().Download(context.Background(), Request{Url: server.URL + "/video.mp4", FilePath: filePath}, func(progress Progress) {})
+
+	if err != nil {
+		t.Fatalf("Error downloading: %s", err)
+	}
+
+	content, _ := os.ReadFile(filePath)
+	if !bytes.Equal(content, testContent) {
+		t.Errorf("Expected the complete part file to be renamed")
+	}
+}
+
+func TestHTTPDownloadCancel(t *testing.T) {
+	rangeRequests := 0
+	server := newTestServer(t, &rangeRequests)
+	filePath := filepath.Join(t.TempDir(), "video.mp4")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// 10KB/s, cancelled long before the download completes
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	err := NewHTTPDownloader().Download(ctx, Request{Url: server.URL + "/video.mp4", FilePath: filePath, RateLimit: 10000}, func(progress Progress) {})
+
+	if err == nil {
+		t.Fatal("Expected an error after cancelling")
+	}
+
+	info, statErr := os.Stat(filePath + ".part")
+	if statErr != nil || info.Size() == 0 || info.Size() >= int64(len(testContent)) {
+		t.Errorf("Expected a partial file to be kept, got %v %v", info, statErr)
+	}
+}
+
+func TestHTTPDownloadNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	err := NewHTTPDownloader().Download(context.Background(), Request{Url: server.URL + "/video.mp4", FilePath: filepath.Join(t.TempDir(), "video.mp4")}, func(progress Progress) {})
+
+	var downloadErr *customErrors.Error
+	if !errors.As(err, &downloadErr) || downloadErr.Reason != customErrors.ReasonRemoved {
+		t.Errorf("Expected a removed error, got %v", err)
+	}
+}
+
+func TestParseDirectMediaUrl(t *testing.T) {
+	if !IsDirectMediaUrl("https://example.com/files/My%20Video.MP4?token=1") {
+		t.Error("Expected an mp4 link to be a direct media url")
+	}
+
+	if IsDirectMediaUrl("https://www.youtube.com/watch?v=abc") || IsDirectMediaUrl("ftp://example.com/video.mp4") {
+		t.Error("Expected only http links to media files to be direct media urls")
+	}
+
+	format, name := ParseDirectMediaUrl("https://example.com/files/My%20Video.MP4?token=1")
+	if format != "mp4" || name != "My Video" {
+		t.Errorf("Expected mp4 and My Video, got %s and %s", format, name)
+	}
+}
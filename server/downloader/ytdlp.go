@@ -0,0 +1,65 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"vidviewer/ytdlp"
+)
+
+// Downloads with the yt-dlp binary, supports any url yt-dlp has an extractor for
+type YtdlpDownloader struct{}
+
+func NewYtdlpDownloader() *YtdlpDownloader {
+	return &YtdlpDownloader{}
+}
+
+func (y *YtdlpDownloader) Supports(url string) bool {
+	return true
+}
+
+func (y *YtdlpDownloader) Download(ctx context.Context, request Request, onProgress ProgressFunc) error {
+	var cmd *exec.Cmd
+
+	if request.Live != "" {
+		cmd = ytdlp.CreateLiveDownloadCommand(ctx, request.Url, request.Format, request.Live, request.FilePath)
+	} else if ytdlp.IsAudioFormat(request.FileFormat) {
+		cmd = ytdlp.CreateAudioDownloadCommand(ctx, request.Url, request.FileFormat, request.FilePath)
+	} else {
+		cmd = ytdlp.CreateDownloadCommand(ctx, request.Url, request.Format, request.FilePath)
+	}
+
+	if request.RateLimit > 0 {
+		ytdlp.SetRateLimit(cmd, request.RateLimit)
+	}
+
+	if request.SponsorBlock != "" {
+		ytdlp.SetSponsorBlock(cmd, request.SponsorBlock, request.FilePath)
+	}
+
+	var downloadErr error
+
+	ytdlp.RunDownload(cmd, onProgress, func() {}, func(err error) {
+		downloadErr = err
+	})
+
+	// The process was killed
+	if ctx.Err() != nil {
+		if request.Live != "" {
+			keepRecording(request.FilePath)
+		}
+		return ctx.Err()
+	}
+
+	return downloadErr
+}
+
+// yt-dlp renames the .part file once it finalized a stopped
+// recording, fragmented streams can be left as .part files
+func keepRecording(filePath string) {
+	if _, err := os.Stat(filePath); err == nil {
+		return
+	}
+
+	os.Rename(filePath+".part", filePath)
+}
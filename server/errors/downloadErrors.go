@@ -0,0 +1,95 @@
+package errors
+
+import (
+	"net/http"
+)
+
+// Reasons a download can fail, sent to the client
+// with the video_download_fail message
+const (
+	ReasonPrivate           = "private"
+	ReasonRemoved           = "removed"
+	ReasonGeoBlocked        = "geo_blocked"
+	ReasonAgeRestricted     = "age_restricted"
+	ReasonRateLimited       = "rate_limited"
+	ReasonFormatUnavailable = "format_unavailable"
+	ReasonNetwork           = "network"
+	ReasonUnknown           = "unknown"
+)
+
+func VideoPrivateError() *Error {
+	return &Error{
+		Message:    "Video is private",
+		StatusCode: http.StatusForbidden,
+		Reason:     ReasonPrivate,
+	}
+}
+
+func VideoRemovedError() *Error {
+	return &Error{
+		Message:    "Video has been removed or does not exist",
+		StatusCode: http.StatusNotFound,
+		Reason:     ReasonRemoved,
+	}
+}
+
+func GeoBlockedError() *Error {
+	return &Error{
+		Message:    "Video is not available in your country",
+		StatusCode: http.StatusForbidden,
+		Reason:     ReasonGeoBlocked,
+	}
+}
+
+func AgeRestrictedError() *Error {
+	return &Error{
+		Message:    "Video is age restricted, add an auth profile for the site to download it",
+		StatusCode: http.StatusForbidden,
+		Reason:     ReasonAgeRestricted,
+	}
+}
+
+func RateLimitedError() *Error {
+	return &Error{
+		Message:    "Too many requests, the site is rate limiting downloads",
+		StatusCode: http.StatusTooManyRequests,
+		Reason:     ReasonRateLimited,
+	}
+}
+
+func FormatUnavailableError() *Error {
+	return &Error{
+		Message:    "Requested format is not available",
+		StatusCode: http.StatusBadRequest,
+		Reason:     ReasonFormatUnavailable,
+	}
+}
+
+func NetworkError() *Error {
+	return &Error{
+		Message:    "Network error, the site could not be reached",
+		StatusCode: http.StatusBadGateway,
+		Reason:     ReasonNetwork,
+	}
+}
+
+// Failures that do not match a known reason, message is yt-dlp's error
+func DownloadFailedError(message string) *Error {
+	return &Error{
+		Message:    message,
+		StatusCode: http.StatusInternalServerError,
+		Reason:     ReasonUnknown,
+	}
+}
+
+// Whether a download that failed for the reason can succeed when
+// it is tried again. Private, removed and restricted videos or a
+// missing format fail the same way every time.
+func IsRetryable(reason string) bool {
+	switch reason {
+	case ReasonNetwork, ReasonRateLimited, ReasonUnknown:
+		return true
+	}
+
+	return false
+}
@@ -3,6 +3,8 @@ package errors
 type Error struct {
     Message    string
     StatusCode int
+    // Why a download failed, see downloadErrors.go
+    Reason     string
 }
 
 func (e *Error) Error() string {
@@ -0,0 +1,22 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOnDeleteVideoWithoutImage(t *testing.T) {
+	root := t.TempDir()
+
+	// An audio only download, there is no thumbnail
+	createFiles(t, filepath.Join(root, "files"), "ab/cd/ef/abcdef.m4a", "ab/cd/ef/abcdef.en.vtt")
+
+	if err := OnDeleteVideo(root, "abcdef", "m4a", "jpg"); err != nil {
+		t.Fatalf("Expected the video to be deleted, got %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "files", "ab")); !os.IsNotExist(err) {
+		t.Errorf("Expected the subtitles and empty folders to be deleted, got %v", err)
+	}
+}
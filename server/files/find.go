@@ -0,0 +1,87 @@
+package files
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// A file found by FindFiles
+type FoundFile struct {
+	Path string
+	// Folder of the file relative to the searched folder, with
+	// forward slashes, eg. "2021/03". Empty for files directly in it.
+	Folder string
+}
+
+// Returns an error if the glob pattern is malformed, see path.Match
+func ValidateGlob(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// Whether a pattern matches the relative path or the name
+func matchesAny(patterns []string, relativePath string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, relativePath); ok {
+			return true
+		}
+
+		if ok, _ := path.Match(pattern, path.Base(relativePath)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the files in folderPath, and in its subfolders if recursive.
+// Patterns are matched against the path relative to folderPath (eg.
+// "2021/*/*.mkv") and the name (eg. "*.mkv"). Files are returned if
+// they match an include pattern, or there are none, and no exclude
+// pattern. Excluded folders are not searched.
+func FindFiles(folderPath string, recursive bool, include []string, exclude []string) ([]FoundFile, error) {
+	found := []FoundFile{}
+
+	err := filepath.WalkDir(folderPath, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if filePath == folderPath {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(folderPath, filePath)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+
+		if entry.IsDir() {
+			if !recursive || matchesAny(exclude, relativePath) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if matchesAny(exclude, relativePath) {
+			return nil
+		}
+
+		if len(include) > 0 && !matchesAny(include, relativePath) {
+			return nil
+		}
+
+		folder := path.Dir(relativePath)
+		if folder == "." {
+			folder = ""
+		}
+
+		found = append(found, FoundFile{Path: filePath, Folder: folder})
+
+		return nil
+	})
+
+	return found, err
+}
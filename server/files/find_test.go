@@ -0,0 +1,75 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createFiles(t *testing.T, root string, names ...string) {
+	for _, name := range names {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("Failed to create folder: %s\n", err)
+		}
+
+		if err := os.WriteFile(filePath, []byte{}, 0644); err != nil {
+			t.Fatalf("Failed to create file: %s\n", err)
+		}
+	}
+}
+
+func foundFolders(found []FoundFile) map[string]string {
+	folders := map[string]string{}
+	for _, file := range found {
+		folders[filepath.Base(file.Path)] = file.Folder
+	}
+	return folders
+}
+
+func TestFindFiles(t *testing.T) {
+	root := t.TempDir()
+
+	createFiles(t, root,
+		"top.mp4",
+		"2021/01/a.mkv",
+		"2021/02/b.mov",
+		"2021/02/b.srt",
+		"drafts/c.avi",
+	)
+
+	found, err := FindFiles(root, false, nil, nil)
+
+	if err != nil {
+		t.Fatalf("Error finding files: %s\n", err)
+	}
+
+	if len(found) != 1 || found[0].Folder != "" {
+		t.Errorf("Expected only the top level file, got %+v", found)
+	}
+
+	found, _ = FindFiles(root, true, nil, []string{"drafts", "*.srt"})
+	folders := foundFolders(found)
+
+	if len(found) != 3 || folders["a.mkv"] != "2021/01" || folders["b.mov"] != "2021/02" {
+		t.Errorf("Expected the files of 2021 with their folders, got %+v", found)
+	}
+
+	found, _ = FindFiles(root, true, []string{"2021/*/*"}, []string{"*.srt"})
+	folders = foundFolders(found)
+
+	if len(found) != 2 || folders["a.mkv"] != "2021/01" {
+		t.Errorf("Expected the included files, got %+v", found)
+	}
+}
+
+func TestValidateGlob(t *testing.T) {
+	if ValidateGlob("*.mkv") != nil {
+		t.Errorf("Expected *.mkv to be valid")
+	}
+
+	if ValidateGlob("[a-") == nil {
+		t.Errorf("Expected [a- to be invalid")
+	}
+}
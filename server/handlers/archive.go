@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"vidviewer/ytdlp"
+)
+
+type ImportArchiveResponse struct {
+	Added   int64 `json:"added"`   // entries that were not in the archive yet
+	Invalid int   `json:"invalid"` // lines that are not "<extractor> <id>"
+}
+
+// Imports a yt-dlp --download-archive file sent as the request body.
+// Subscriptions and playlist imports skip the videos in the archive.
+func ImportArchive(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).ArchiveRepo
+
+	entries, invalid, err := ytdlp.ParseArchive(r.Body)
+
+	if err != nil {
+		log.Println("Error reading archive", err)
+		http.Error(w, "Error reading archive", http.StatusBadRequest)
+		return
+	}
+
+	added, err := repo.Add(entries)
+
+	if err != nil {
+		log.Println("Error importing archive", err)
+		http.Error(w, "Failed to import archive", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ImportArchiveResponse{Added: added, Invalid: invalid})
+}
+
+// Writes a yt-dlp --download-archive file with the imported
+// entries and the videos downloaded with yt-dlp
+func ExportArchive(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).ArchiveRepo
+
+	entries, err := repo.Export()
+
+	if err != nil {
+		log.Println("Error exporting archive", err)
+		http.Error(w, "Failed to export archive", http.StatusInternalServerError)
+		return
+	}
+
+	var archive bytes.Buffer
+	ytdlp.WriteArchive(&archive, entries)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="archive.txt"`)
+	w.Write(archive.Bytes())
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"vidviewer/config"
+
+	"github.com/gorilla/mux"
+)
+
+type AuthProfileFormData struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Domains  []string `json:"domains"`
+	Machine  string   `json:"machine"`
+	Username string   `json:"username"`
+	// Content of the cookies.txt file for cookies profiles,
+	// the password for netrc profiles. Never returned.
+	Secret string `json:"secret"`
+}
+
+// The profile as returned by the API, without its secret
+type AuthProfileResponse struct {
+	config.AuthProfile
+	HasSecret bool `json:"has_secret"`
+}
+
+func newAuthProfileResponse(profile config.AuthProfile) AuthProfileResponse {
+	return AuthProfileResponse{AuthProfile: profile, HasSecret: profile.HasSecret()}
+}
+
+func (data AuthProfileFormData) profile() config.AuthProfile {
+	profile := config.AuthProfile{
+		Name:    strings.TrimSpace(data.Name),
+		Type:    data.Type,
+		Domains: data.Domains,
+	}
+
+	if data.Type == config.AuthProfileNetrc {
+		profile.Machine = strings.TrimSpace(data.Machine)
+		profile.Username = strings.TrimSpace(data.Username)
+	}
+
+	return profile
+}
+
+func GetAuthProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := config.LoadAuthProfiles()
+
+	if err != nil {
+		log.Println("Error loading auth profiles", err)
+		http.Error(w, "Failed to fetch auth profiles", http.StatusInternalServerError)
+		return
+	}
+
+	response := []AuthProfileResponse{}
+	for _, profile := range profiles {
+		response = append(response, newAuthProfileResponse(profile))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func CreateAuthProfile(w http.ResponseWriter, r *http.Request) {
+	var data AuthProfileFormData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		log.Println("Error parsing JSON data")
+		http.Error(w, "Error parsing JSON data", http.StatusBadRequest)
+		return
+	}
+
+	profile := data.profile()
+
+	errors := []string{}
+
+	if err := profile.Validate(); err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	if strings.TrimSpace(data.Secret) == "" {
+		errors = append(errors, "Secret cannot be blank")
+	}
+
+	if existing, _ := config.GetAuthProfile(profile.Name); existing != nil {
+		errors = append(errors, "Auth profile already exists")
+	}
+
+	if len(errors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: errors})
+		return
+	}
+
+	err = config.SaveAuthProfile(profile, data.Secret)
+
+	if err != nil {
+		log.Println("Error saving auth profile", err)
+		http.Error(w, "Failed to save auth profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newAuthProfileResponse(profile))
+}
+
+// Updates the profile, the secret is kept if not set
+func UpdateAuthProfile(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	existing, err := config.GetAuthProfile(name)
+
+	if err != nil || existing == nil {
+		http.Error(w, "Auth profile not found", http.StatusNotFound)
+		return
+	}
+
+	var data AuthProfileFormData
+	err = json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		log.Println("Error parsing JSON data")
+		http.Error(w, "Error parsing JSON data", http.StatusBadRequest)
+		return
+	}
+
+	// Profiles are renamed by deleting and creating them
+	data.Name = name
+	profile := data.profile()
+
+	errors := []string{}
+
+	if err := profile.Validate(); err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	if profile.Type != existing.Type && strings.TrimSpace(data.Secret) == "" {
+		errors = append(errors, "Secret cannot be blank when changing the type")
+	}
+
+	if len(errors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: errors})
+		return
+	}
+
+	err = config.SaveAuthProfile(profile, data.Secret)
+
+	if err != nil {
+		log.Println("Error saving auth profile", err)
+		http.Error(w, "Failed to save auth profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAuthProfileResponse(profile))
+}
+
+func DeleteAuthProfile(w http.ResponseWriter, r *http.Request) {
+	deleted, err := config.DeleteAuthProfile(mux.Vars(r)["name"])
+
+	if err != nil {
+		log.Println("Error deleting auth profile", err)
+		http.Error(w, "Failed to delete auth profile", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Auth profile not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
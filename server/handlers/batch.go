@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"vidviewer/config"
+	"vidviewer/downloadManager"
+	"vidviewer/downloader"
+	customErrors "vidviewer/errors"
+	"vidviewer/files"
+	"vidviewer/middleware"
+	"vidviewer/models"
+	"vidviewer/repository"
+	ws "vidviewer/websocket"
+	"vidviewer/ytdlp"
+)
+
+// Result of each url of a batch
+const (
+	batchQueued    = "queued"
+	batchDuplicate = "duplicate" // in the library or earlier in the batch
+	batchArchived  = "archived"  // in the download archive, see ArchiveRepository, only sent over the websocket
+	batchInvalid   = "invalid"
+	batchFailed    = "failed" // the video could not be saved
+)
+
+type BatchVideoFormData struct {
+	URLs       []string `json:"urls"`
+	PlaylistID int      `json:"playlist_id"`
+	Format     string   `json:"format"`
+}
+
+type BatchVideoResult struct {
+	Url    string `json:"url"`
+	Status string `json:"status"`
+	// The queued video, or the existing one for duplicates
+	VideoID int64  `json:"video_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BatchVideoResponse struct {
+	Results []BatchVideoResult `json:"results"`
+}
+
+// Reads a JSON body, or a text/plain body with one url per line
+// and the playlist_id and format in the query string
+func parseBatchVideoForm(r *http.Request) (BatchVideoFormData, error) {
+	var data BatchVideoFormData
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "text/plain") {
+		err := json.NewDecoder(r.Body).Decode(&data)
+		return data, err
+	}
+
+	queryParams := r.URL.Query()
+
+	data.PlaylistID, _ = strconv.Atoi(queryParams.Get("playlist_id"))
+	data.Format = queryParams.Get("format")
+
+	scanner := bufio.NewScanner(r.Body)
+
+	for scanner.Scan() {
+		data.URLs = append(data.URLs, scanner.Text())
+	}
+
+	return data, scanner.Err()
+}
+
+// Adds every url to the library and the download queue. Each
+// url is downloaded as a single video, playlist and channel
+// urls have to be added with CreateVideo. Only urls already in
+// the library are reported as duplicates in the response, other
+// copies of a video are removed once its metadata is read.
+func CreateVideoBatch(w http.ResponseWriter, r *http.Request) {
+	repositories := GetRepositories(r)
+	rootFolderPath := r.Context().Value(middleware.ConfigKey).(config.Config).FolderPath
+	dm := r.Context().Value(middleware.DownloadManagerKey).(*downloadManager.DownloadManager)
+
+	data, err := parseBatchVideoForm(r)
+	if err != nil {
+		log.Println("Error parsing batch data")
+		http.Error(w, "Error parsing batch data", http.StatusBadRequest)
+		return
+	}
+
+	errors := []string{}
+
+	if data.PlaylistID < 1 {
+		errors = append(errors, "Invalid playlist")
+	} else if _, err := repositories.PlaylistRepo.Get(fmt.Sprint(data.PlaylistID)); err != nil {
+		errors = append(errors, "Could not find playlist")
+	}
+
+	if len(data.URLs) == 0 {
+		errors = append(errors, "URLs cannot be blank")
+	}
+
+	if len(errors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: errors})
+		return
+	}
+
+	ytdlpInstalled := ytdlp.IsInstalled()
+
+	// Video id of each url seen in this batch
+	seen := map[string]int64{}
+
+	response := BatchVideoResponse{Results: []BatchVideoResult{}}
+	queued := []int64{}
+
+	for _, url := range data.URLs {
+		url = strings.TrimSpace(url)
+
+		if url == "" {
+			continue
+		}
+
+		result := BatchVideoResult{Url: url}
+
+		if videoID, ok := seen[url]; ok {
+			result.Status = batchDuplicate
+			result.VideoID = videoID
+		} else if !isValidURL(url) {
+			result.Status = batchInvalid
+			result.Error = "Invalid URL"
+		} else if !downloader.IsDirectMediaUrl(url) && !ytdlpInstalled {
+			result.Status = batchFailed
+			result.Error = "yt-dlp is not installed"
+		} else {
+			result = createBatchVideo(url, data, repositories)
+		}
+
+		if result.Status == batchQueued {
+			queued = append(queued, result.VideoID)
+		}
+
+		if result.VideoID > 0 {
+			seen[url] = result.VideoID
+		}
+
+		response.Results = append(response.Results, result)
+	}
+
+	// yt-dlp takes seconds per url, the response is not kept waiting
+	go resolveBatchVideos(queued, repositories, rootFolderPath, dm)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Adds the video of the url to the library with the url as title,
+// unless the url is in the library. The video is queued once
+// resolveBatchVideos has read its metadata.
+func createBatchVideo(url string, data BatchVideoFormData, repositories *repository.Repositories) BatchVideoResult {
+	result := BatchVideoResult{Url: url}
+
+	existingVideo, err := repositories.VideoRepo.GetBy(url, "url")
+
+	if err == nil && existingVideo != nil {
+		result.Status = batchDuplicate
+		result.VideoID = existingVideo.ID
+		return result
+	}
+
+	info := ytdlp.VideoInfo{Title: url}
+
+	if downloader.IsDirectMediaUrl(url) {
+		_, name := downloader.ParseDirectMediaUrl(url)
+		info = ytdlp.VideoInfo{Title: name, WebpageUrl: url}
+	}
+
+	formData := NewVideoFormData{
+		Source:     "ytdlp",
+		URL:        url,
+		Format:     data.Format,
+		PlaylistID: data.PlaylistID,
+	}
+
+	video, err := createYtdlpVideo(url, info, formData, repositories)
+
+	if err != nil {
+		log.Println("Error creating batch video:", url, err)
+		result.Status = batchFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = batchQueued
+	result.VideoID = video.ID
+
+	return result
+}
+
+// Reads the metadata of each video and queues it, one at a time so a
+// large batch does not run yt-dlp for every url at once. Videos that
+// are playlists, in the library under another url, or in the archive
+// are removed and reported with a VideoDownloadFail message.
+func resolveBatchVideos(videoIDs []int64, repositories *repository.Repositories, rootFolderPath string, dm *downloadManager.DownloadManager) {
+	for _, videoID := range videoIDs {
+		video, err := repositories.VideoRepo.GetBy(fmt.Sprint(videoID), "id")
+
+		// Deleted in the meantime
+		if err != nil || video == nil {
+			continue
+		}
+
+		// Direct media urls have nothing to resolve
+		if video.WebpageUrl == "" {
+			// Resolves the extractor and id of the video,
+			// youtu.be/X and youtube.com/watch?v=X are the same
+			flatInfo, err := ytdlp.GetFlatInfo(video.Url)
+
+			if err == nil && flatInfo.IsPlaylist() {
+				removeBatchVideo(*video, batchInvalid, "Playlist and channel urls have to be added one at a time", repositories)
+				continue
+			}
+
+			var info *ytdlp.VideoInfo
+
+			if err == nil {
+				info, err = flatInfo.VideoInfo()
+			}
+
+			if err != nil {
+				reason := customErrors.ReasonUnknown
+				var downloadErr *customErrors.Error
+				if errors.As(err, &downloadErr) && downloadErr.Reason != "" {
+					reason = downloadErr.Reason
+				}
+				removeBatchVideo(*video, reason, err.Error(), repositories)
+				continue
+			}
+
+			if existing, _ := repositories.VideoRepo.GetBySource(info.ExtractorKey, info.ID); existing != nil && existing.ID != video.ID {
+				removeBatchVideo(*video, batchDuplicate, fmt.Sprintf("Video is already in the library as video %d", existing.ID), repositories)
+				continue
+			}
+
+			if archived, _ := repositories.ArchiveRepo.Has(info.ExtractorKey, info.ID); archived {
+				removeBatchVideo(*video, batchArchived, "Video is in the download archive", repositories)
+				continue
+			}
+
+			applyVideoInfo(video, *info)
+
+			if err := repositories.VideoRepo.Update(*video); err != nil {
+				removeBatchVideo(*video, batchFailed, err.Error(), repositories)
+				continue
+			}
+
+			saveYtdlpChapters(video.ID, info.Chapters, repositories.ChapterRepo)
+		}
+
+		err = LoadVideoWithYtdlp(*video, repositories, rootFolderPath, files.GetTemporaryFolderPath(rootFolderPath), dm)
+
+		if err != nil {
+			log.Println("Error queueing batch video:", video.Url, err)
+		}
+	}
+}
+
+// Removes a batch video that is not downloaded
+func removeBatchVideo(video models.Video, reason string, message string, repositories *repository.Repositories) {
+	log.Println("Batch video not queued:", video.Url, message)
+
+	repositories.PlaylistVideoRepo.OnDeleteVideo(strconv.FormatInt(video.ID, 10))
+	repositories.ChapterRepo.OnDeleteVideo(strconv.FormatInt(video.ID, 10))
+	repositories.VideoRepo.Delete(strconv.FormatInt(video.ID, 10))
+
+	ws.CurrentHub.WriteToClients(ws.WebsocketMessage{
+		Type: string(ws.VideoDownloadFail),
+		Payload: ws.VideoDownloadFailPayload{
+			VideoID: video.ID,
+			Title:   video.Title,
+			Url:     video.Url,
+			Reason:  reason,
+			Message: message,
+		},
+	})
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"vidviewer/models"
+	"vidviewer/repository"
+	"vidviewer/ytdlp"
+
+	"github.com/gorilla/mux"
+)
+
+type ChapterFormData struct {
+	Title     string  `json:"title"`
+	StartTime float64 `json:"start_time"`
+	// Optional, defaults to the start of the next chapter
+	EndTime float64 `json:"end_time"`
+}
+
+func GetVideoChapters(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).ChapterRepo
+
+	chapters, err := repo.GetByVideo(mux.Vars(r)["id"])
+
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to fetch chapters", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chapters)
+}
+
+// Replaces the chapters of the video with the chapters in the body
+func UpdateVideoChapters(w http.ResponseWriter, r *http.Request) {
+	repositories := GetRepositories(r)
+	videoID := mux.Vars(r)["id"]
+
+	video, err := repositories.VideoRepo.Get(videoID)
+
+	if err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	var data []ChapterFormData
+	err = json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		log.Println("Error parsing JSON data")
+		http.Error(w, "Error parsing JSON data", http.StatusBadRequest)
+		return
+	}
+
+	errors := []string{}
+	chapters := []models.Chapter{}
+
+	for i, chapter := range data {
+		if strings.TrimSpace(chapter.Title) == "" {
+			errors = append(errors, fmt.Sprintf("Chapter %d: title cannot be blank", i+1))
+		}
+
+		if chapter.StartTime < 0 {
+			errors = append(errors, fmt.Sprintf("Chapter %d: start time cannot be negative", i+1))
+		}
+
+		if chapter.EndTime != 0 && chapter.EndTime <= chapter.StartTime {
+			errors = append(errors, fmt.Sprintf("Chapter %d: end time must be after the start time", i+1))
+		}
+
+		chapters = append(chapters, models.Chapter{
+			VideoID:   video.ID,
+			Title:     strings.TrimSpace(chapter.Title),
+			StartTime: chapter.StartTime,
+			EndTime:   chapter.EndTime,
+		})
+	}
+
+	if len(errors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: errors})
+		return
+	}
+
+	chapters = normalizeChapters(chapters, parseDuration(video.Duration))
+
+	err = repositories.ChapterRepo.SetVideoChapters(video.ID, chapters)
+
+	if err != nil {
+		log.Println("Error saving chapters", err)
+		http.Error(w, "Failed to save chapters", http.StatusInternalServerError)
+		return
+	}
+
+	chapters, _ = repositories.ChapterRepo.GetByVideo(videoID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chapters)
+}
+
+// Serves the chapters as a WebVTT chapters track
+func GetVideoChaptersVtt(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).ChapterRepo
+
+	chapters, err := repo.GetByVideo(mux.Vars(r)["id"])
+
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to fetch chapters", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt")
+	w.Write([]byte(chaptersToVtt(chapters)))
+}
+
+func chaptersToVtt(chapters []models.Chapter) string {
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+
+	for i, chapter := range chapters {
+		fmt.Fprintf(&vtt, "\n%d\n%s --> %s\n%s\n", i+1, formatVttTimestamp(chapter.StartTime), formatVttTimestamp(chapter.EndTime), escapeVttText(chapter.Title))
+	}
+
+	return vtt.String()
+}
+
+var vttTextReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Escapes the title for a cue, a line break or a blank line would end
+// the cue and "-->" is escaped to "--&gt;" so it is not read as timings
+func escapeVttText(text string) string {
+	return vttTextReplacer.Replace(strings.Join(strings.Fields(text), " "))
+}
+
+// Formats seconds as hh:mm:ss.ttt
+func formatVttTimestamp(seconds float64) string {
+	milliseconds := int64(seconds*1000 + 0.5)
+
+	return fmt.Sprintf(
+		"%02d:%02d:%02d.%03d",
+		milliseconds/3600000,
+		milliseconds/60000%60,
+		milliseconds/1000%60,
+		milliseconds%1000,
+	)
+}
+
+// Parses a duration formatted by formatDuration (h:mm:ss, m:ss or s) to seconds
+func parseDuration(duration string) float64 {
+	seconds := 0.0
+
+	for _, part := range strings.Split(duration, ":") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + value
+	}
+
+	return seconds
+}
+
+// Sorts the chapters by start time and sets missing end times to
+// the start of the next chapter, or the duration for the last one
+func normalizeChapters(chapters []models.Chapter, duration float64) []models.Chapter {
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].StartTime < chapters[j].StartTime
+	})
+
+	for i := range chapters {
+		if chapters[i].EndTime > 0 {
+			continue
+		}
+
+		if i+1 < len(chapters) {
+			chapters[i].EndTime = chapters[i+1].StartTime
+		} else if duration > chapters[i].StartTime {
+			chapters[i].EndTime = duration
+		} else {
+			chapters[i].EndTime = chapters[i].StartTime
+		}
+	}
+
+	return chapters
+}
+
+// Saves the chapters from the yt-dlp metadata
+func saveYtdlpChapters(videoID int64, ytdlpChapters []ytdlp.Chapter, chapterRepository repository.ChapterRepository) {
+	if len(ytdlpChapters) == 0 {
+		return
+	}
+
+	chapters := []models.Chapter{}
+
+	for _, chapter := range ytdlpChapters {
+		chapters = append(chapters, models.Chapter{
+			VideoID:   videoID,
+			Title:     chapter.Title,
+			StartTime: chapter.StartTime,
+			EndTime:   chapter.EndTime,
+		})
+	}
+
+	err := chapterRepository.SetVideoChapters(videoID, normalizeChapters(chapters, 0))
+
+	if err != nil {
+		log.Println("Error saving chapters", err)
+	}
+}
+
+type ProbeChapters struct {
+	Chapters []struct {
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+		Tags      struct {
+			Title string `json:"title"`
+		} `json:"tags"`
+	} `json:"chapters"`
+}
+
+// Saves the chapters embedded in the file,
+// unless the video already has chapters
+func saveFileChapters(videoID int64, path string, chapterRepository repository.ChapterRepository) {
+	existingChapters, err := chapterRepository.GetByVideo(strconv.FormatInt(videoID, 10))
+
+	if err != nil || len(existingChapters) > 0 {
+		return
+	}
+
+	cmd := exec.Command("ffprobe", "-v", "error", "-print_format", "json", "-show_chapters", path)
+	output, err := cmd.Output()
+
+	if err != nil {
+		log.Println("Error reading chapters:", err)
+		return
+	}
+
+	probeChapters := ProbeChapters{}
+	err = json.Unmarshal(output, &probeChapters)
+
+	if err != nil {
+		log.Println("Error parsing chapters:", err)
+		return
+	}
+
+	chapters := []models.Chapter{}
+
+	for i, probeChapter := range probeChapters.Chapters {
+		startTime, _ := strconv.ParseFloat(probeChapter.StartTime, 64)
+		endTime, _ := strconv.ParseFloat(probeChapter.EndTime, 64)
+
+		title := probeChapter.Tags.Title
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+
+		chapters = append(chapters, models.Chapter{
+			VideoID:   videoID,
+			Title:     title,
+			StartTime: startTime,
+			EndTime:   endTime,
+		})
+	}
+
+	if len(chapters) == 0 {
+		return
+	}
+
+	err = chapterRepository.SetVideoChapters(videoID, normalizeChapters(chapters, 0))
+
+	if err != nil {
+		log.Println("Error saving chapters", err)
+	}
+}
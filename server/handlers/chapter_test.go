@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"testing"
+	"vidviewer/models"
+)
+
+func TestChaptersToVtt(t *testing.T) {
+	chapters := []models.Chapter{
+		{Title: "Intro", StartTime: 0, EndTime: 61.5},
+		{Title: "Q&A <live>\n\nPart 1 --> 2", StartTime: 61.5, EndTime: 3723},
+	}
+
+	expected := "WEBVTT\n" +
+		"\n1\n00:00:00.000 --> 00:01:01.500\nIntro\n" +
+		"\n2\n00:01:01.500 --> 01:02:03.000\nQ&amp;A &lt;live&gt; Part 1 --&gt; 2\n"
+
+	if vtt := chaptersToVtt(chapters); vtt != expected {
+		t.Errorf("Expected\n%q\ngot\n%q", expected, vtt)
+	}
+}
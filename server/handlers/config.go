@@ -9,12 +9,21 @@ import (
 	"vidviewer/config"
 	customErrors "vidviewer/errors"
 	"vidviewer/middleware"
+	"vidviewer/ytdlp"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type UpdateConfigFormData struct {
 	RootFolderPath string `json:"root_folder_path"`
+	MaxConcurrentDownloads int `json:"max_concurrent_downloads"`
+	MaxDownloadAttempts int `json:"max_download_attempts"`
+	RetryBackoffSeconds int `json:"retry_backoff_seconds"`
+	// Pointers as 0 (unlimited) and an empty schedule are valid values
+	RateLimit *int64 `json:"rate_limit"`
+	RateLimitSchedule *[]config.RateLimitWindow `json:"rate_limit_schedule"`
+	// "mark", "remove" or "" to disable
+	SponsorBlockMode *string `json:"sponsorblock_mode"`
 }
 
 func GetConfig(w http.ResponseWriter, r *http.Request) {
@@ -58,6 +67,44 @@ func UpdateConfig(w http.ResponseWriter, r *http.Request) {
 
 	c.FolderPath = rootFolderPath
 
+	if formData.MaxConcurrentDownloads > 0 {
+		c.MaxConcurrentDownloads = formData.MaxConcurrentDownloads
+	}
+
+	if formData.MaxDownloadAttempts > 0 {
+		c.MaxDownloadAttempts = formData.MaxDownloadAttempts
+	}
+
+	if formData.RetryBackoffSeconds > 0 {
+		c.RetryBackoffSeconds = formData.RetryBackoffSeconds
+	}
+
+	if formData.RateLimit != nil {
+		if *formData.RateLimit < 0 {
+			http.Error(w, "Rate limit cannot be negative", http.StatusBadRequest)
+			return
+		}
+		c.RateLimit = *formData.RateLimit
+	}
+
+	if formData.RateLimitSchedule != nil {
+		for _, window := range *formData.RateLimitSchedule {
+			if err := window.Validate(); err != nil {
+				http.Error(w, "Invalid rate limit schedule: " + err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		c.RateLimitSchedule = *formData.RateLimitSchedule
+	}
+
+	if formData.SponsorBlockMode != nil {
+		if *formData.SponsorBlockMode != "" && !ytdlp.IsSponsorBlockMode(*formData.SponsorBlockMode) {
+			http.Error(w, "Invalid SponsorBlock mode", http.StatusBadRequest)
+			return
+		}
+		c.SponsorBlockMode = *formData.SponsorBlockMode
+	}
+
 	config.Update(c)
 
 	log.Println("Config Update Succesful, current root folder path is: " + c.FolderPath)
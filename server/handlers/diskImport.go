@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type ProbeVideo struct {
+	Streams []struct {
+		CodecType   string `json:"codec_type"`
+		Disposition struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+	} `json:"format"`
+}
+
+// Formats ffprobe reads video streams from that are not videos
+var imageFormatSuffixes = []string{"image2", "_pipe", "gif"}
+
+// Whether the file has a video stream that plays for some time,
+// cover art, still images and gifs are not videos
+func (p ProbeVideo) isPlayable() bool {
+	for _, suffix := range imageFormatSuffixes {
+		if strings.HasSuffix(p.Format.FormatName, suffix) {
+			return false
+		}
+	}
+
+	if p.duration() <= 0 {
+		return false
+	}
+
+	for _, stream := range p.Streams {
+		if stream.CodecType == "video" && stream.Disposition.AttachedPic == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Duration in seconds, 0 if unknown
+func (p ProbeVideo) duration() float64 {
+	duration, _ := strconv.ParseFloat(p.Format.Duration, 64)
+	return duration
+}
+
+// Reads the streams and format of the file with ffprobe
+func probeVideo(path string) (ProbeVideo, error) {
+	probe := ProbeVideo{}
+
+	cmd := exec.Command(
+		"ffprobe", "-v", "error", "-print_format", "json",
+		"-show_entries", "format=format_name,duration:stream=codec_type:stream_disposition=attached_pic",
+		path,
+	)
+	output, err := cmd.Output()
+
+	if err != nil {
+		return probe, err
+	}
+
+	err = json.Unmarshal(output, &probe)
+
+	return probe, err
+}
+
+// File format of an imported file, its extension or the
+// first name of the container ffprobe detected, eg. "matroska"
+func importedFileFormat(path string, probe ProbeVideo) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+
+	if ext != "" {
+		return ext
+	}
+
+	return strings.Split(probe.Format.FormatName, ",")[0]
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 	"vidviewer/config"
 	"vidviewer/downloadManager"
+	"vidviewer/downloader"
+	customErrors "vidviewer/errors"
 	"vidviewer/files"
 	"vidviewer/middleware"
+	"vidviewer/models"
+	"vidviewer/repository"
+	"vidviewer/ytdlp"
 
 	"github.com/gorilla/mux"
 )
@@ -16,7 +23,6 @@ func ResumeDownload(w http.ResponseWriter, r *http.Request) {
 	rootFolderPath := r.Context().Value(middleware.ConfigKey).(config.Config).FolderPath
 	dm := r.Context().Value(middleware.DownloadManagerKey).(*downloadManager.DownloadManager)
 	videoRepo := repositories.VideoRepo
-	playlistVideoRepo :=  repositories.PlaylistVideoRepo
 	tempFolderPath := files.GetTemporaryFolderPath(rootFolderPath)
 
 	vars := mux.Vars(r)
@@ -32,18 +38,123 @@ func ResumeDownload(w http.ResponseWriter, r *http.Request) {
 	if (err != nil) {
 		log.Println("Could not find video with id: ", videoId)
 		http.Error(w, "Video not found", http.StatusBadRequest)
+		return
+	}
+
+	if !downloader.IsDirectMediaUrl(video.Url) && !ytdlp.IsInstalled() {
+		ytdlpNotFound(w)
+		return
+	}
+
+	err = videoRepo.SetDownloadPaused(video.ID, false)
+
+	if err != nil {
+		log.Println("Error updating paused state of video:", err)
+	}
+
+	video.DownloadPaused = false
+
+	// Continue the download if it was paused while the server 
+	// was running, otherwise start it again with the partial file
+	resumed, _ := dm.OnResumeDownload(videoId)
+
+	if resumed {
+		return
+	}
+
+	// A re-download from before a restart, the format is not stored
+	if video.DownloadComplete {
+		http.Error(w, "Download not found, start the re-download again", http.StatusBadRequest)
+		return
 	}
 
 	LoadVideoWithYtdlp(
 		*video,
-		playlistVideoRepo,
-		videoRepo,
+		repositories,
 		rootFolderPath,
 		tempFolderPath,
 		dm,
 	)
 }
 
+// Starts a download again that was interrupted by a shutdown,
+// used by the download manager when it is initialized
+func ResumeInterruptedDownload(video models.Video, repositories *repository.Repositories, dm *downloadManager.DownloadManager) error {
+	if !downloader.IsDirectMediaUrl(video.Url) && !ytdlp.IsInstalled() {
+		return customErrors.YtdlpNotFoundError()
+	}
+
+	rootFolderPath := config.Load().FolderPath
+
+	return LoadVideoWithYtdlp(
+		video,
+		repositories,
+		rootFolderPath,
+		files.GetTemporaryFolderPath(rootFolderPath),
+		dm,
+	)
+}
+
+func PauseDownload(w http.ResponseWriter, r *http.Request) {
+	repositories := GetRepositories(r)
+	dm := r.Context().Value(middleware.DownloadManagerKey).(*downloadManager.DownloadManager)
+	videoRepo := repositories.VideoRepo
+
+	vars := mux.Vars(r)
+	videoId := vars["video_id"]
+
+	if videoId == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	download, err := dm.PauseDownload(videoId)
+
+	if err != nil {
+		log.Println("Error trying to pause download:", err)
+		http.Error(w, "Error trying to pause download", http.StatusBadRequest)
+		return
+	}
+
+	// Re-downloads are not continued after a restart
+	if download.Video.DownloadComplete {
+		return
+	}
+
+	// Keep the paused state after a restart
+	err = videoRepo.SetDownloadPaused(download.Video.ID, true)
+
+	if err != nil {
+		log.Println("Error updating paused state of video:", err)
+		http.Error(w, "Error trying to pause download", http.StatusInternalServerError)
+	}
+}
+
+// Stops a live recording, the recorded file is
+// added to the library like a finished download
+func StopRecording(w http.ResponseWriter, r *http.Request) {
+	dm := r.Context().Value(middleware.DownloadManagerKey).(*downloadManager.DownloadManager)
+
+	videoId := mux.Vars(r)["video_id"]
+
+	if videoId == "" {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	_, err := dm.StopRecording(videoId)
+
+	if err != nil {
+		log.Println("Error trying to stop recording:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Cancels the download of the video, the video is
+// deleted unless it was downloaded before
 func CancelDownload(w http.ResponseWriter, r *http.Request) {
 	repositories := GetRepositories(r)
     dm := r.Context().Value(middleware.DownloadManagerKey).(*downloadManager.DownloadManager)
@@ -63,9 +174,91 @@ func CancelDownload(w http.ResponseWriter, r *http.Request) {
 
 	if error != nil  {
 		http.Error(w, "Error trying to cancel download", http.StatusInternalServerError)
+	} else if download.Video.DownloadComplete {
+		// Re-download in another format, the video keeps its current file
+		files.OnCancelDownload(rootFolderPath, download.Video.FileID)
 	} else {
 		playlistVideoRepo.OnDeleteVideo(videoId)
+		repositories.ChapterRepo.OnDeleteVideo(videoId)
+		repositories.SponsorBlockSegmentRepo.OnDeleteVideo(videoId)
 		videoRepo.Delete(videoId) 
 		files.OnCancelDownload(rootFolderPath, download.Video.FileID) // delete temp files
 	}
-} 
\ No newline at end of file
+} 
+
+// Returns the download history (records), newest first. The
+// other /downloads/{video_id} routes take the video's id.
+// Query params: page, limit, status, video_id, search, since, until (unix time)
+func GetDownloads(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).DownloadRepo
+
+	queryParams := r.URL.Query()
+
+	pageStr := queryParams.Get("page")
+	if pageStr == "" {
+		pageStr = "1"
+	}
+	limitStr := queryParams.Get("limit")
+	if limitStr == "" {
+		limitStr = "20"
+	}
+
+	page, _ := strconv.ParseUint(pageStr, 10, 0)
+	limit, _ := strconv.ParseUint(limitStr, 10, 0)
+
+	filter := repository.DownloadFilter{
+		Status: queryParams.Get("status"),
+		Search: queryParams.Get("search"),
+	}
+	filter.VideoID, _ = strconv.ParseInt(queryParams.Get("video_id"), 10, 64)
+	filter.Since, _ = strconv.ParseInt(queryParams.Get("since"), 10, 64)
+	filter.Until, _ = strconv.ParseInt(queryParams.Get("until"), 10, 64)
+
+	downloads, err := repo.Index(filter, uint(limit), uint(page))
+
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error getting downloads", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(downloads)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// Returns a download record of the history by its own id
+func GetDownloadRecord(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).DownloadRepo
+
+	id := mux.Vars(r)["id"]
+
+	download, err := repo.Get(id)
+
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error getting download", http.StatusInternalServerError)
+		return
+	}
+
+	if download == nil {
+		http.Error(w, "Download not found", http.StatusNotFound)
+		return
+	}
+
+	jsonData, err := json.Marshal(download)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
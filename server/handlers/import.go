@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"vidviewer/imports"
+	"vidviewer/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+func getImportManager(r *http.Request) *imports.Manager {
+	return r.Context().Value(middleware.ImportManagerKey).(*imports.Manager)
+}
+
+// Returns the status of a disk import started by CreateVideo
+func GetImport(w http.ResponseWriter, r *http.Request) {
+	job, ok := getImportManager(r).Get(mux.Vars(r)["id"])
+
+	if !ok {
+		http.Error(w, "Import not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// Stops the import after the file being imported, the files
+// already imported are kept. The job is "cancelled" once stopped.
+func CancelImport(w http.ResponseWriter, r *http.Request) {
+	job, ok := getImportManager(r).Cancel(mux.Vars(r)["id"])
+
+	if !ok {
+		http.Error(w, "Import not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"vidviewer/config"
+	"vidviewer/downloadManager"
+	"vidviewer/downloader"
+	"vidviewer/files"
+	"vidviewer/middleware"
+	"vidviewer/models"
+	"vidviewer/repository"
+	ws "vidviewer/websocket"
+	"vidviewer/ytdlp"
+
+	"github.com/gorilla/mux"
+)
+
+type RedownloadFormData struct {
+	// yt-dlp format id, see /video_formats
+	Format string `json:"format"`
+}
+
+// Downloads a complete video again in another format. The video
+// can be watched while downloading, the file is swapped once
+// the new version is complete.
+func RedownloadVideo(w http.ResponseWriter, r *http.Request) {
+	repositories := GetRepositories(r)
+	rootFolderPath := r.Context().Value(middleware.ConfigKey).(config.Config).FolderPath
+	dm := r.Context().Value(middleware.DownloadManagerKey).(*downloadManager.DownloadManager)
+	videoID := mux.Vars(r)["id"]
+
+	video, err := repositories.VideoRepo.Get(videoID)
+
+	if err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	var data RedownloadFormData
+	err = json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		log.Println("Error parsing JSON data")
+		http.Error(w, "Error parsing JSON data", http.StatusBadRequest)
+		return
+	}
+
+	errors := []string{}
+
+	if data.Format == "" {
+		errors = append(errors, "Format cannot be blank")
+	}
+
+	if video.Url == "" || downloader.IsDirectMediaUrl(video.Url) {
+		errors = append(errors, "Only videos downloaded with yt-dlp can be downloaded in another format")
+	} else if ytdlp.IsAudioFormat(video.FileFormat) {
+		errors = append(errors, "Audio downloads cannot be downloaded in another format")
+	}
+
+	if !video.DownloadComplete {
+		errors = append(errors, "Video has not finished downloading")
+	}
+
+	if d := dm.GetDownload(videoID); d != nil && !d.IsComplete && !d.IsCancelled && !d.IsError {
+		errors = append(errors, "Video is already downloading")
+	}
+
+	if len(errors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: errors})
+		return
+	}
+
+	if !ytdlp.IsInstalled() {
+		ytdlpNotFound(w)
+		return
+	}
+
+	redownloadVideo(*video, data.Format, repositories, rootFolderPath, files.GetTemporaryFolderPath(rootFolderPath), dm)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Queues the download of the video in format. Only the file, format,
+// checksum, duration and SponsorBlock segments are replaced, the old
+// file is kept if the download fails or is cancelled.
+func redownloadVideo(video models.Video, format string, repositories *repository.Repositories, rootFolderPath string, tempFolderPath string, dm *downloadManager.DownloadManager) {
+	downloadVideoPath := filepath.Join(tempFolderPath, video.FileID+".redownload."+video.FileFormat)
+
+	video.VideoFormat = sql.NullString{String: format, Valid: true}
+
+	download, _ := dm.AddNewDownload(video)
+
+	// Final failure, only the partial files are removed
+	onDownloadError := func(err error) {
+		log.Println("Re-download failed:", video.Url, err)
+		dm.FailDownload(download, err)
+		files.OnCancelDownload(rootFolderPath, video.FileID)
+
+		ws.CurrentHub.WriteToClients(ws.WebsocketMessage{
+			Type: string(ws.VideoDownloadFail),
+			Payload: ws.VideoDownloadFailPayload{
+				VideoID: video.ID,
+				Title:   video.Title,
+				Url:     video.Url,
+				Reason:  download.ErrorReason,
+				Message: download.ErrorMsg,
+			},
+		})
+	}
+
+	onDownloadRunError := func(err error) {
+		// yt-dlp may write to the partial file until it exits
+		if download.IsCancelled {
+			files.OnCancelDownload(rootFolderPath, video.FileID)
+			return
+		}
+
+		if download.IsPaused {
+			return
+		}
+
+		if dm.RetryDownload(download, err) {
+			return
+		}
+
+		onDownloadError(err)
+	}
+
+	onDownloadComplete := func() {
+		md5Checksum, err := computeChecksum(downloadVideoPath)
+
+		if err != nil {
+			onDownloadError(err)
+			return
+		}
+
+		duration, err := getVideoDuration(downloadVideoPath)
+
+		if err != nil {
+			onDownloadError(err)
+			return
+		}
+
+		// Reloaded so changes made while downloading (eg. the title) are kept
+		currentVideo, err := repositories.VideoRepo.Get(strconv.FormatInt(video.ID, 10))
+
+		if err != nil {
+			log.Println("Video was deleted while downloading:", video.ID)
+			onDownloadError(err)
+			return
+		}
+
+		videoPath := files.GetFilePath(rootFolderPath, video.FileID, video.FileFormat)
+
+		err = files.SwapFile(downloadVideoPath, videoPath)
+
+		if err != nil {
+			log.Println("Error replacing video file:", err)
+			onDownloadError(err)
+			return
+		}
+
+		currentVideo.VideoFormat = video.VideoFormat
+		currentVideo.Md5Checksum = md5Checksum
+		currentVideo.Duration = duration
+
+		// The segments and chapters are replaced with the ones of the new file
+		saveSponsorBlockInfo(currentVideo, ytdlp.SponsorBlockInfoPath(downloadVideoPath), videoPath, repositories)
+
+		err = repositories.VideoRepo.Update(*currentVideo)
+
+		if err != nil {
+			log.Println("Error while updating video:", err)
+		}
+
+		dm.CompleteDownload(download)
+
+		ws.CurrentHub.WriteToClients(ws.WebsocketMessage{Type: string(ws.VideoDownloadSuccess)})
+	}
+
+	download.Request = downloader.Request{
+		Url:        video.Url,
+		Format:     format,
+		FileFormat: video.FileFormat,
+		FilePath:   downloadVideoPath,
+		// The new file is cut or marked like the current one
+		SponsorBlock: video.SponsorBlockMode,
+	}
+
+	// Re-downloads are not resumed on the next start,
+	// the current file is kept and the partial one removed
+	download.OnShutdown = func() {
+		files.OnCancelDownload(rootFolderPath, video.FileID)
+	}
+
+	dm.Enqueue(download, onDownloadComplete, onDownloadRunError)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"vidviewer/models"
+	"vidviewer/repository"
+	"vidviewer/ytdlp"
+
+	"github.com/gorilla/mux"
+)
+
+// Disables SponsorBlock for a download when the config has a default mode
+const sponsorBlockNone = "none"
+
+// Segments of a video downloaded in "mark" mode, for the player to skip
+func GetVideoSponsorBlockSegments(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).SponsorBlockSegmentRepo
+
+	segments, err := repo.GetByVideo(mux.Vars(r)["id"])
+
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to fetch segments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(segments)
+}
+
+// Saves the SponsorBlock metadata yt-dlp wrote after the download.
+// In "mark" mode the segments are stored, in "remove" mode the
+// duration and chapters are updated to match the cut file.
+// Errors are logged as the video itself downloaded fine.
+func saveSponsorBlockInfo(video *models.Video, infoPath string, videoPath string, repositories *repository.Repositories) {
+	if video.SponsorBlockMode == "" {
+		return
+	}
+
+	defer os.Remove(infoPath)
+
+	if video.SponsorBlockMode == ytdlp.SponsorBlockRemove {
+		duration, err := getVideoDuration(videoPath)
+		if err == nil {
+			video.Duration = duration
+		} else {
+			log.Println("Error extracting duration:", err)
+		}
+	}
+
+	info, err := ytdlp.ReadSponsorBlockInfo(infoPath)
+
+	if err != nil {
+		// No segments, or not a YouTube video
+		log.Println("Error reading SponsorBlock info:", err)
+		return
+	}
+
+	if video.SponsorBlockMode == ytdlp.SponsorBlockRemove {
+		// The chapters saved when the video was created are for the uncut video
+		repositories.ChapterRepo.OnDeleteVideo(strconv.FormatInt(video.ID, 10))
+		saveYtdlpChapters(video.ID, info.Chapters, repositories.ChapterRepo)
+		return
+	}
+
+	segments := []models.SponsorBlockSegment{}
+
+	for _, segment := range info.Segments {
+		segments = append(segments, models.SponsorBlockSegment{
+			VideoID:   video.ID,
+			Category:  segment.Category,
+			Action:    segment.Type,
+			StartTime: segment.StartTime,
+			EndTime:   segment.EndTime,
+		})
+	}
+
+	err = repositories.SponsorBlockSegmentRepo.SetVideoSegments(video.ID, segments)
+
+	if err != nil {
+		log.Println("Error saving SponsorBlock segments", err)
+	}
+}
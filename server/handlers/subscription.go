@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"vidviewer/config"
+	"vidviewer/downloadManager"
+	"vidviewer/middleware"
+	"vidviewer/models"
+	"vidviewer/repository"
+	"vidviewer/subscriptions"
+	"vidviewer/ytdlp"
+
+	"github.com/gorilla/mux"
+)
+
+// How often a subscription is checked if not set
+const defaultSubscriptionIntervalMinutes = 360
+
+// Shortest allowed interval, to be polite to the sites
+const minSubscriptionIntervalMinutes = 15
+
+type SubscriptionFormData struct {
+	Url             string `json:"url"`
+	Name            string `json:"name"`
+	PlaylistID      int64  `json:"playlist_id"`
+	Format          string `json:"format"`
+	IntervalMinutes int    `json:"interval_minutes"`
+	// Queue the videos already in the channel or playlist on the
+	// first check, only the videos uploaded later are by default.
+	// Also applies when an update changes the url.
+	DownloadExisting bool `json:"download_existing"`
+}
+
+// Adds new videos found by the subscription poller
+// to the library and the download queue
+func QueueSubscriptionVideos(subscription models.Subscription, videos []ytdlp.FlatInfo, repositories *repository.Repositories, dm *downloadManager.DownloadManager) {
+	rootFolderPath := config.Load().FolderPath
+
+	data := NewVideoFormData{
+		Source:     "ytdlp",
+		Format:     subscription.Format,
+		PlaylistID: int(subscription.PlaylistID),
+	}
+
+	for _, entry := range videos {
+		_, err := queueYtdlpVideo(entry, data, repositories, rootFolderPath, dm)
+
+		if err != nil {
+			log.Println("Error queueing subscription video:", entry.Url, err)
+		}
+	}
+}
+
+func validateSubscriptionForm(data SubscriptionFormData, r repository.PlaylistRepository) []string {
+	var errors []string
+
+	if data.Url == "" {
+		errors = append(errors, "URL cannot be blank")
+	} else if !isValidURL(data.Url) {
+		errors = append(errors, "Invalid URL")
+	}
+
+	if data.PlaylistID < 1 {
+		errors = append(errors, "Invalid playlist")
+	} else {
+		_, err := r.Get(fmt.Sprint(data.PlaylistID))
+		if err != nil {
+			errors = append(errors, "Could not find playlist")
+		}
+	}
+
+	if data.IntervalMinutes != 0 && data.IntervalMinutes < minSubscriptionIntervalMinutes {
+		errors = append(errors, fmt.Sprintf("Interval must be at least %d minutes", minSubscriptionIntervalMinutes))
+	}
+
+	return errors
+}
+
+func GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).SubscriptionRepo
+
+	subscriptions, err := repo.Index()
+
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to fetch subscriptions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+func GetSubscription(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).SubscriptionRepo
+
+	subscription, err := repo.Get(mux.Vars(r)["id"])
+
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to fetch subscription", http.StatusInternalServerError)
+		return
+	}
+
+	if subscription == nil {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+func CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	repositories := GetRepositories(r)
+	poller := r.Context().Value(middleware.SubscriptionPollerKey).(*subscriptions.Poller)
+
+	if !ytdlp.IsInstalled() {
+		ytdlpNotFound(w)
+		return
+	}
+
+	var data SubscriptionFormData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		log.Println("Error parsing JSON data")
+		http.Error(w, "Error parsing JSON data", http.StatusBadRequest)
+		return
+	}
+
+	errors := validateSubscriptionForm(data, repositories.PlaylistRepo)
+
+	if len(errors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: errors})
+		return
+	}
+
+	if data.IntervalMinutes == 0 {
+		data.IntervalMinutes = defaultSubscriptionIntervalMinutes
+	}
+
+	subscription := models.Subscription{
+		Url:             data.Url,
+		Name:            data.Name,
+		PlaylistID:      data.PlaylistID,
+		Format:          data.Format,
+		IntervalMinutes: data.IntervalMinutes,
+		CreatedAt:       time.Now().Unix(),
+	}
+
+	// The existing videos are recorded by Seed, the poller
+	// retries it until it succeeds
+	if !data.DownloadExisting {
+		subscription.LastChecked = subscription.CreatedAt
+		subscription.NeedsSeed = true
+	}
+
+	subscription.ID, err = repositories.SubscriptionRepo.Create(subscription)
+
+	if err != nil {
+		log.Println("Error creating subscription", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: []string{"Subscription already exists"}})
+		return
+	}
+
+	if subscription.NeedsSeed {
+		go seedSubscription(poller, subscription)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// Records the existing videos of the subscription in the background,
+// yt-dlp can take a while for large channels
+func seedSubscription(poller *subscriptions.Poller, subscription models.Subscription) {
+	count, err := poller.Seed(subscription)
+
+	if err != nil {
+		log.Println("Error recording subscription videos, retried at the next check:", subscription.Url, err)
+		return
+	}
+
+	log.Printf("subscription %s has %d existing videos", subscription.Url, count)
+}
+
+func UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	repositories := GetRepositories(r)
+	poller := r.Context().Value(middleware.SubscriptionPollerKey).(*subscriptions.Poller)
+
+	subscription, err := repositories.SubscriptionRepo.Get(mux.Vars(r)["id"])
+
+	if err != nil || subscription == nil {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+
+	var data SubscriptionFormData
+	err = json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		log.Println("Error parsing JSON data")
+		http.Error(w, "Error parsing JSON data", http.StatusBadRequest)
+		return
+	}
+
+	errors := validateSubscriptionForm(data, repositories.PlaylistRepo)
+
+	if len(errors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: errors})
+		return
+	}
+
+	if data.IntervalMinutes == 0 {
+		data.IntervalMinutes = defaultSubscriptionIntervalMinutes
+	}
+
+	// The videos of the new url are recorded like on creation
+	if data.Url != subscription.Url && !data.DownloadExisting {
+		subscription.NeedsSeed = true
+	}
+
+	subscription.Url = data.Url
+	subscription.Name = data.Name
+	subscription.PlaylistID = data.PlaylistID
+	subscription.Format = data.Format
+	subscription.IntervalMinutes = data.IntervalMinutes
+
+	err = repositories.SubscriptionRepo.Update(*subscription)
+
+	if err != nil {
+		log.Println("Error updating subscription", err)
+		http.Error(w, "Failed to update subscription", http.StatusInternalServerError)
+		return
+	}
+
+	if subscription.NeedsSeed {
+		go seedSubscription(poller, *subscription)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+func DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).SubscriptionRepo
+
+	err := repo.Delete(mux.Vars(r)["id"])
+
+	if err != nil {
+		log.Println("Error deleting subscription", err)
+		http.Error(w, "Failed to delete subscription", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Checks the subscription for new videos now,
+// instead of waiting for its interval
+func CheckSubscription(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).SubscriptionRepo
+	poller := r.Context().Value(middleware.SubscriptionPollerKey).(*subscriptions.Poller)
+
+	subscription, err := repo.Get(mux.Vars(r)["id"])
+
+	if err != nil || subscription == nil {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+
+	if !ytdlp.IsInstalled() {
+		ytdlpNotFound(w)
+		return
+	}
+
+	// yt-dlp can take a while for large channels
+	go func() {
+		_, err := poller.Check(*subscription)
+		if err != nil {
+			log.Println("Error checking subscription:", subscription.Url, err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
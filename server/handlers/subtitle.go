@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"vidviewer/config"
+	"vidviewer/files"
+	"vidviewer/middleware"
+	"vidviewer/models"
+	"vidviewer/repository"
+	"vidviewer/ytdlp"
+
+	"github.com/gorilla/mux"
+)
+
+// Language used for sidecar subtitle files without one in the name
+const undefinedSubtitleLanguage = "und"
+
+// Language codes, eg. "en", "pt-BR"
+var subtitleLanguageRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,16}$`)
+
+func isValidSubtitleLanguage(language string) bool {
+	return subtitleLanguageRegex.MatchString(language)
+}
+
+func GetVideoSubtitles(w http.ResponseWriter, r *http.Request) {
+	repo := GetRepositories(r).SubtitleRepo
+
+	subtitles, err := repo.GetByVideo(mux.Vars(r)["id"])
+
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to fetch subtitles", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subtitles)
+}
+
+// Streams the WebVTT file of the subtitle track
+func GetVideoSubtitle(w http.ResponseWriter, r *http.Request) {
+	repositories := GetRepositories(r)
+	rootFolderPath := r.Context().Value(middleware.ConfigKey).(config.Config).FolderPath
+
+	vars := mux.Vars(r)
+
+	video, err := repositories.VideoRepo.Get(vars["id"])
+
+	if err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	subtitle, err := repositories.SubtitleRepo.Get(vars["id"], vars["language"])
+
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to fetch subtitle", http.StatusInternalServerError)
+		return
+	}
+
+	if subtitle == nil {
+		http.Error(w, "Subtitle not found", http.StatusNotFound)
+		return
+	}
+
+	subtitleFile, err := os.Open(files.GetSubtitlePath(rootFolderPath, video.FileID, subtitle.Language))
+
+	if err != nil {
+		http.Error(w, "Failed to open subtitle file", http.StatusInternalServerError)
+		return
+	}
+
+	defer subtitleFile.Close()
+
+	stat, err := subtitleFile.Stat()
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt")
+	http.ServeContent(w, r, subtitle.Language+".vtt", stat.ModTime(), subtitleFile)
+}
+
+// Downloads the subtitles the video was created with into the video's
+// folder. Auto generated subtitles are only used for languages without
+// a manual one. Errors are logged as the video itself downloaded fine.
+func downloadYtdlpSubtitles(video models.Video, tempFolderPath string, rootFolderPath string, subtitleRepository repository.SubtitleRepository) {
+	if len(video.SubtitleLanguages) == 0 {
+		return
+	}
+
+	downloadPath := filepath.Join(tempFolderPath, video.FileID)
+
+	languages, err := ytdlp.DownloadSubtitles(video.Url, video.SubtitleLanguages, false, downloadPath)
+
+	if err != nil {
+		log.Println("Error downloading subtitles:", err)
+		languages = []string{}
+	}
+
+	saveSubtitles := func(languages []string, autoGenerated bool) {
+		for _, language := range languages {
+			err := files.MoveFile(downloadPath+"."+language+".vtt", files.GetSubtitlePath(rootFolderPath, video.FileID, language))
+
+			if err != nil {
+				log.Println("Error moving subtitle file from temp folder", err)
+				continue
+			}
+
+			_, err = subtitleRepository.Create(models.Subtitle{
+				VideoID:       video.ID,
+				Language:      language,
+				AutoGenerated: autoGenerated,
+			})
+
+			if err != nil {
+				log.Println("Error saving subtitle", err)
+			}
+		}
+	}
+
+	saveSubtitles(languages, false)
+
+	if !video.AutoSubtitles {
+		return
+	}
+
+	missingLanguages := []string{}
+
+	for _, language := range video.SubtitleLanguages {
+		if !contains(languages, language) {
+			missingLanguages = append(missingLanguages, language)
+		}
+	}
+
+	if len(missingLanguages) == 0 {
+		return
+	}
+
+	autoLanguages, err := ytdlp.DownloadSubtitles(video.Url, missingLanguages, true, downloadPath)
+
+	if err != nil {
+		log.Println("Error downloading auto generated subtitles:", err)
+		return
+	}
+
+	saveSubtitles(autoLanguages, true)
+}
+
+// Imports the .srt/.vtt files next to the video file, named like
+// the video with an optional language, eg. video.srt or video.en.vtt
+func importSidecarSubtitles(videoPath string, video models.Video, rootFolderPath string, subtitleRepository repository.SubtitleRepository) {
+	subtitlePaths, err := findSidecarSubtitles(videoPath)
+
+	if err != nil {
+		log.Println("Error reading folder for subtitles", err)
+		return
+	}
+
+	for language, path := range subtitlePaths {
+		err := convertToVtt(path, files.GetSubtitlePath(rootFolderPath, video.FileID, language))
+
+		if err != nil {
+			log.Println("Error converting subtitle", path, err)
+			continue
+		}
+
+		_, err = subtitleRepository.Create(models.Subtitle{
+			VideoID:  video.ID,
+			Language: language,
+		})
+
+		if err != nil {
+			log.Println("Error saving subtitle", err)
+		}
+	}
+}
+
+// Returns the path of the subtitle file of each language next to
+// the video. If a language has a .srt and a .vtt file the .vtt
+// file is used, it is copied as is instead of converted.
+func findSidecarSubtitles(videoPath string) (map[string]string, error) {
+	folderPath := filepath.Dir(videoPath)
+	baseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+
+	entries, err := os.ReadDir(folderPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	subtitlePaths := map[string]string{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+
+		if entry.IsDir() || (ext != ".srt" && ext != ".vtt") || !strings.HasPrefix(name, baseName+".") {
+			continue
+		}
+
+		language := strings.TrimPrefix(strings.TrimSuffix(name[len(baseName):], filepath.Ext(name)), ".")
+
+		if language == "" {
+			language = undefinedSubtitleLanguage
+		}
+
+		if !isValidSubtitleLanguage(language) {
+			continue
+		}
+
+		path := filepath.Join(folderPath, name)
+
+		if existing, ok := subtitlePaths[language]; ok {
+			if ext == ".vtt" && strings.ToLower(filepath.Ext(existing)) != ".vtt" {
+				existing, path = path, existing
+				subtitlePaths[language] = existing
+			}
+
+			log.Println("Skipping subtitle, the language has another file:", path, existing)
+			continue
+		}
+
+		subtitlePaths[language] = path
+	}
+
+	return subtitlePaths, nil
+}
+
+// Converts a subtitle file to WebVTT with FFMPEG, .vtt files are copied
+func convertToVtt(from string, to string) error {
+	if strings.ToLower(filepath.Ext(from)) == ".vtt" {
+		return files.CopyFile(from, to)
+	}
+
+	cmd := exec.Command("ffmpeg", "-y", "-i", from, "-f", "webvtt", to)
+	output, err := cmd.CombinedOutput()
+
+	if err != nil {
+		log.Println("FFMPEG Error:", err)
+		log.Println("Output:", string(output))
+		return err
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindSidecarSubtitles(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"video.mkv", "video.srt", "video.en.srt", "video.en.vtt", "video.de.srt", "other.fr.srt", "video.txt"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte{}, 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s: %s\n", name, err)
+		}
+	}
+
+	subtitlePaths, err := findSidecarSubtitles(filepath.Join(dir, "video.mkv"))
+
+	if err != nil {
+		t.Fatalf("Error finding subtitles: %s", err)
+	}
+
+	expected := map[string]string{
+		undefinedSubtitleLanguage: "video.srt",
+		"en":                      "video.en.vtt",
+		"de":                      "video.de.srt",
+	}
+
+	if len(subtitlePaths) != len(expected) {
+		t.Errorf("Expected %d subtitles, got %v", len(expected), subtitlePaths)
+	}
+
+	for language, name := range expected {
+		if subtitlePaths[language] != filepath.Join(dir, name) {
+			t.Errorf("Expected %s for %q, got %q", name, language, subtitlePaths[language])
+		}
+	}
+}
@@ -21,7 +21,10 @@ import (
 	"time"
 	"vidviewer/config"
 	"vidviewer/downloadManager"
+	"vidviewer/downloader"
+	customErrors "vidviewer/errors"
 	"vidviewer/files"
+	"vidviewer/imports"
 	"vidviewer/middleware"
 	"vidviewer/models"
 	"vidviewer/repository"
@@ -77,7 +80,10 @@ func GetVideosFromPlaylist(w http.ResponseWriter, r *http.Request) {
 	page, _ := strconv.ParseUint(pageStr, 10, 0)
 	limit, _ := strconv.ParseUint(limitStr, 10, 0)
 
-	videos, err := repo.GetFromPlaylist(playlistID, uint(limit), uint(page), like, uint(sortBy))
+	// Only videos no longer available at their url
+	sourceRemoved := queryParams.Get("source_removed") == "true"
+
+	videos, err := repo.GetFromPlaylist(playlistID, uint(limit), uint(page), like, uint(sortBy), sourceRemoved)
 
 	if (err != nil) {
 		log.Println(err)
@@ -170,6 +176,30 @@ func DeleteVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Delete the video's subtitle tracks
+	err = GetRepositories(r).SubtitleRepo.OnDeleteVideo(id)
+
+    if err != nil {
+		http.Error(w, "Failed to delete subtitles", http.StatusInternalServerError)
+		return
+	}
+
+	// Delete the video's chapters
+	err = GetRepositories(r).ChapterRepo.OnDeleteVideo(id)
+
+    if err != nil {
+		http.Error(w, "Failed to delete chapters", http.StatusInternalServerError)
+		return
+	}
+
+	// Delete the video's SponsorBlock segments
+	err = GetRepositories(r).SponsorBlockSegmentRepo.OnDeleteVideo(id)
+
+    if err != nil {
+		http.Error(w, "Failed to delete SponsorBlock segments", http.StatusInternalServerError)
+		return
+	}
+
 	// Delete the video from the database based on the ID
 	err = videoRepo.Delete(id)
 
@@ -220,8 +250,7 @@ func GetVideo(w http.ResponseWriter, r *http.Request) {
 	defer videoFile.Close()
 
 	// Set the Content-Type header based on the video file extension
-	contentType := "video/mp4" // Change this based on the actual file type
-	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Type", getContentType(video.FileFormat))
 
 	stat, err := videoFile.Stat()
 		if err != nil {
@@ -232,10 +261,43 @@ func GetVideo(w http.ResponseWriter, r *http.Request) {
 	http.ServeContent(w, r, video.Title, stat.ModTime(), videoFile)
 }
 
+func ytdlpNotFound(w http.ResponseWriter) {
+	error := customErrors.YtdlpNotFoundError()
+	http.Error(w, error.Error(), error.StatusCode)
+}
+
+func getContentType(fileFormat string) string {
+	switch fileFormat {
+	case "webm":
+		return "video/webm"
+	case "m4a":
+		return "audio/mp4"
+	case "mp3":
+		return "audio/mpeg"
+	case "opus":
+		return "audio/ogg"
+	case "mkv":
+		return "video/x-matroska"
+	case "mov":
+		return "video/quicktime"
+	case "m4v":
+		return "video/x-m4v"
+	case "avi":
+		return "video/x-msvideo"
+	default:
+		return "video/mp4"
+	}
+}
+
 func GetVideoFormats(w http.ResponseWriter, r *http.Request) {
 	// Get the value of the "url" parameter from the URL query string
 	urlParam := r.URL.Query().Get("url")
 
+	if !ytdlp.IsInstalled() {
+		ytdlpNotFound(w)
+		return
+	}
+
   // TEMPORARY
 	formats, err := ytdlp.GetFormats(urlParam)
 
@@ -276,19 +338,93 @@ type NewVideoFormData struct {
   Folder     string   `json:"folder"`
   URL        string   `json:"url"`
   Format     string   `json:"format"`
+  // For playlist/channel urls, create a playlist named after 
+  // it instead of adding the videos to PlaylistID
+  CreatePlaylist bool `json:"create_playlist"`
+  // Only download the audio, extracted to AudioFormat (m4a, mp3 or opus)
+  AudioOnly   bool   `json:"audio_only"`
+  AudioFormat string `json:"audio_format"`
+  // Subtitle languages to download, eg. ["en", "de"]. With 
+  // AutoSubtitles, auto generated subtitles are used for 
+  // languages that have no manual subtitles.
+  SubtitleLanguages []string `json:"subtitle_languages"`
+  AutoSubtitles     bool     `json:"auto_subtitles"`
+  // Bytes per second, overrides the global rate limit if > 0
+  RateLimit int64 `json:"rate_limit"`
+  // "mark", "remove" or "none", the default from the config if empty
+  SponsorBlockMode string `json:"sponsorblock_mode"`
+  // Records a live stream from "now" or from the "start" of the 
+  // stream, until it ends or is stopped. Streams that are live 
+  // are recorded from now if empty.
+  Live string `json:"live"`
+  // Disk imports also search the subfolders of Folder
+  Recursive bool `json:"recursive"`
+  // Glob patterns matched against the path relative to Folder 
+  // and the file name, eg. "2021/*" or "*.mkv". Only files 
+  // matching an Include pattern, if any, are imported.
+  Include []string `json:"include"`
+  Exclude []string `json:"exclude"`
+}
+
+// Extension of the downloaded file
+func (data NewVideoFormData) fileFormat() string {
+	if downloader.IsDirectMediaUrl(data.URL) {
+		fileFormat, _ := downloader.ParseDirectMediaUrl(data.URL)
+		return fileFormat
+	}
+
+	if !data.AudioOnly {
+		return "mp4"
+	}
+
+	if data.AudioFormat == "" {
+		return ytdlp.DefaultAudioFormat
+	}
+
+	return data.AudioFormat
+}
+
+// SponsorBlock mode of the download, empty if disabled
+func (data NewVideoFormData) sponsorBlockMode() string {
+	if downloader.IsDirectMediaUrl(data.URL) || data.Live != "" {
+		return ""
+	}
+
+	switch data.SponsorBlockMode {
+	case "":
+		return config.Load().SponsorBlockMode
+	case sponsorBlockNone:
+		return ""
+	default:
+		return data.SponsorBlockMode
+	}
+}
+
+// Response when a playlist/channel url is expanded into videos
+type NewPlaylistVideosResponse struct {
+	PlaylistID int64   `json:"playlist_id"`
+	Queued     []int64 `json:"queued"`   // ids of videos added to the download queue
+	Existing   []int64 `json:"existing"` // ids of videos already in the library
+	Archived   int     `json:"archived"` // videos skipped as they are in the download archive
 }
 
 type ErrorResponse struct {
 	Errors []string `json:"errors"`
 }
 
+// Returned when the video is already in the library,
+// possibly under another url
+type VideoExistsResponse struct {
+	ErrorResponse
+	Video *models.Video `json:"video"`
+}
+
 func CreateVideo(w http.ResponseWriter, r *http.Request) {
 	repositories := GetRepositories(r)
 	rootFolderPath := r.Context().Value(middleware.ConfigKey).(config.Config).FolderPath
 	dm := r.Context().Value(middleware.DownloadManagerKey).(*downloadManager.DownloadManager)
 	videoRepository := repositories.VideoRepo
 	playlistRepository := repositories.PlaylistRepo 
-	playlistVideoRepository :=  repositories.PlaylistVideoRepo
 	tempFolderPath := files.GetTemporaryFolderPath(rootFolderPath)
 
 	body, err := io.ReadAll(r.Body)
@@ -319,69 +455,119 @@ func CreateVideo(w http.ResponseWriter, r *http.Request) {
 
   switch data.Source {
   case "disk":
-    loadError := loadVideosFromDisk(
-		data.Folder, 
-		fmt.Sprint(data.PlaylistID),
-		playlistVideoRepository,
-		videoRepository,
-		rootFolderPath, 
-	)
+	// Only the folder is searched here, the files are 
+	// probed and copied by the import job in the background
+	foundFiles, err := files.FindFiles(data.Folder, data.Recursive, data.Include, data.Exclude)
+
+	if err != nil || len(foundFiles) == 0 {
+		message := "folder does not contain video files"
+		if err != nil {
+			message = err.Error()
+		}
 
-	if loadError != nil{
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Errors: []string{loadError.Error()}})
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: []string{message}})
 		return
 	}
-  case "ytdlp":
-	video, err := videoRepository.GetBy(data.URL, "url")
+
+	im := getImportManager(r)
+	playlistID := fmt.Sprint(data.PlaylistID)
+
+	job, err := im.Start(data.Folder, foundFiles, func(foundFile files.FoundFile) imports.FileResult {
+		return importFileFromDisk(foundFile, playlistID, repositories, rootFolderPath)
+	})
 
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Errors: []string{err.Error()}})
+		log.Println("Error starting import", err)
+		http.Error(w, "Failed to start import", http.StatusInternalServerError)
 		return
 	}
 
-	if video != nil && video.DownloadComplete {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Errors: []string{"Video exists"}})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+  case "ytdlp":
+	// Direct links to media files are downloaded without yt-dlp
+	isDirectUrl := downloader.IsDirectMediaUrl(data.URL)
+
+	if !isDirectUrl && !ytdlp.IsInstalled() {
+		ytdlpNotFound(w)
 		return
 	}
 
-	if video == nil {
-		duration, title, nil := ytdlp.ExtractVideoInfo(data.URL)
-		currentDate := time.Now().Format("2006-01-02 15:04:05")
-		fileID, _ := generateFileID()
+	var info *ytdlp.FlatInfo
+	var infoErr error
 
-		video = &models.Video {
-			VideoFormat: sql.NullString{String: data.Format, Valid: true},
-			DownloadDate: currentDate, 
-			FileID: fileID, 
-			Url: data.URL,
-			Title: title,
-			Duration: duration,
-			DownloadComplete: false,
-			FileFormat: "mp4",
-		}
+	if !isDirectUrl {
+		info, infoErr = ytdlp.GetFlatInfo(data.URL)
+	}
 
-		videoID, err := videoRepository.Create(*video)
-		video.ID = videoID;
+	// Playlist and channel urls are added as one video per entry
+	if !isDirectUrl && infoErr == nil && info.IsPlaylist() {
+		response, err := loadPlaylistWithYtdlp(*info, data, repositories, rootFolderPath, dm)
 
 		if err != nil {
-			log.Println("Error inserting video into videos table", err)
+			log.Println("Error loading playlist", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(ErrorResponse{Errors: []string{err.Error()}})
-			return 
+			return
 		}
 
-		// Create PlaylistVideo 
-		_, err = playlistVideoRepository.Create(fmt.Sprint(data.PlaylistID), fmt.Sprint(video.ID))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var videoInfo *ytdlp.VideoInfo
+
+	if isDirectUrl {
+		// Named after the file, WebpageUrl is set so the 
+		// metadata is not fetched with yt-dlp later
+		_, name := downloader.ParseDirectMediaUrl(data.URL)
+		videoInfo = &ytdlp.VideoInfo{Title: name, WebpageUrl: data.URL}
+	} else {
+		if infoErr == nil {
+			videoInfo, infoErr = info.VideoInfo()
+		}
+
+		// The metadata is fetched again when the download completes
+		if infoErr != nil {
+			log.Println("Error getting video info:", infoErr)
+			videoInfo = &ytdlp.VideoInfo{}
+		}
+	}
+
+	if data.Live == "" && !data.AudioOnly && videoInfo.IsLive() {
+		data.Live = ytdlp.LiveFromNow
+	}
+
+	// Other urls of the same video are matched by the extractor and its id
+	video, err := videoRepository.GetExisting(data.URL, videoInfo.ExtractorKey, videoInfo.ID)
+
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Errors: []string{err.Error()}})
+		return
+	}
+
+	// An incomplete video is downloaded again, unless it is still in the queue
+	if video != nil && (video.DownloadComplete || dm.IsDownloadActive(fmt.Sprint(video.ID))) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(VideoExistsResponse{
+			ErrorResponse: ErrorResponse{Errors: []string{"Video exists"}},
+			Video:         video,
+		})
+		return
+	}
+
+	if video == nil {
+		video, err = createYtdlpVideo(data.URL, *videoInfo, data, repositories)
 
 		if err != nil {
-			log.Println("Error creating playlistvideo", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(ErrorResponse{Errors: []string{err.Error()}})
@@ -391,8 +577,7 @@ func CreateVideo(w http.ResponseWriter, r *http.Request) {
 
   	ytdlpError := LoadVideoWithYtdlp(
 		*video, 
-		playlistVideoRepository,
-		videoRepository,
+		repositories,
 		rootFolderPath,
 		tempFolderPath,
     	dm,
@@ -407,33 +592,182 @@ func CreateVideo(w http.ResponseWriter, r *http.Request) {
   }
 }
 
-func getFilesWithExtensions(folderPath string, extensions []string) ([]string, error) {
-	files, err := ioutil.ReadDir(folderPath)
+// Inserts the video row for a yt-dlp download with the 
+// options of the form and adds it to the playlist (if data.PlaylistID > 0)
+func createYtdlpVideo(url string, info ytdlp.VideoInfo, data NewVideoFormData, repositories *repository.Repositories) (*models.Video, error) {
+	currentDate := time.Now().Format("2006-01-02 15:04:05")
+	fileID, _ := generateFileID()
+
+	video := &models.Video {
+		VideoFormat: sql.NullString{String: data.Format, Valid: true},
+		DownloadDate: currentDate, 
+		FileID: fileID, 
+		Url: url,
+		DownloadComplete: false,
+		FileFormat: data.fileFormat(),
+		SubtitleLanguages: data.SubtitleLanguages,
+		AutoSubtitles: data.AutoSubtitles,
+		RateLimit: data.RateLimit,
+		SponsorBlockMode: data.sponsorBlockMode(),
+		LiveMode: data.Live,
+	}
+
+	applyVideoInfo(video, info)
+
+	videoID, err := repositories.VideoRepo.Create(*video)
+	video.ID = videoID;
+
 	if err != nil {
+		log.Println("Error inserting video into videos table", err)
 		return nil, err
 	}
 
-	var paths []string
-	for _, file := range files {
-		if !file.IsDir() {
-			ext := strings.ToLower(filepath.Ext(file.Name()))
-			for _, extension := range extensions {
-				if ext == extension {
-					paths = append(paths, filepath.Join(folderPath, file.Name()))
-					break
-				}
+	saveYtdlpChapters(video.ID, info.Chapters, repositories.ChapterRepo)
+
+	if data.PlaylistID < 1 {
+		return video, nil
+	}
+
+	// Create PlaylistVideo 
+	_, err = repositories.PlaylistVideoRepo.Create(fmt.Sprint(data.PlaylistID), fmt.Sprint(video.ID))
+
+	if err != nil {
+		log.Println("Error creating playlistvideo", err)
+		return nil, err
+	}
+
+	return video, nil
+}
+
+// Adds every video of a yt-dlp playlist/channel to the library 
+// and the download queue. Videos already in the library are 
+// only added to the playlist.
+func loadPlaylistWithYtdlp(info ytdlp.FlatInfo, data NewVideoFormData, repositories *repository.Repositories, rootFolderPath string, dm *downloadManager.DownloadManager) (NewPlaylistVideosResponse, error) {
+	response := NewPlaylistVideosResponse{
+		PlaylistID: int64(data.PlaylistID),
+		Queued: []int64{},
+		Existing: []int64{},
+	}
+
+	if data.CreatePlaylist {
+		playlistID, err := createPlaylistWithUniqueName(repositories.PlaylistRepo, info.Title)
+		if err != nil {
+			return response, err
+		}
+		response.PlaylistID = playlistID
+		data.PlaylistID = int(playlistID)
+	}
+
+	playlistID := fmt.Sprint(response.PlaylistID)
+
+	for _, entry := range ytdlp.GetPlaylistVideos(info) {
+		existingVideo, err := repositories.VideoRepo.GetExisting(entry.Url, entry.IEKey, entry.ID)
+
+		if err != nil {
+			log.Println("Error checking for existing video:", entry.Url, err)
+			continue
+		}
+
+		if existingVideo != nil {
+			if _, err := repositories.PlaylistVideoRepo.Get(playlistID, fmt.Sprint(existingVideo.ID)); err == sql.ErrNoRows {
+				repositories.PlaylistVideoRepo.Create(playlistID, fmt.Sprint(existingVideo.ID))
 			}
+			response.Existing = append(response.Existing, existingVideo.ID)
+			continue
+		}
+
+		// Downloaded before with yt-dlp, see ImportArchive
+		if archived, _ := repositories.ArchiveRepo.Has(entry.IEKey, entry.ID); archived {
+			response.Archived++
+			continue
+		}
+
+		video, err := queueYtdlpVideo(entry, data, repositories, rootFolderPath, dm)
+
+		if err != nil {
+			continue
 		}
+
+		response.Queued = append(response.Queued, video.ID)
+	}
+
+	return response, nil
+}
+
+// Adds a video from a yt-dlp playlist/channel to 
+// the library and the download queue
+func queueYtdlpVideo(entry ytdlp.FlatInfo, data NewVideoFormData, repositories *repository.Repositories, rootFolderPath string, dm *downloadManager.DownloadManager) (*models.Video, error) {
+	// The rest of the metadata is fetched when the download completes
+	info := ytdlp.VideoInfo{Title: entry.Title, Duration: entry.Duration, ID: entry.ID, ExtractorKey: entry.IEKey}
+
+	video, err := createYtdlpVideo(entry.Url, info, data, repositories)
+
+	if err != nil {
+		return nil, err
 	}
 
-	return paths, nil
+	err = LoadVideoWithYtdlp(
+		*video,
+		repositories,
+		rootFolderPath,
+		files.GetTemporaryFolderPath(rootFolderPath),
+		dm,
+	)
+
+	return video, err
+}
+
+// Copies the yt-dlp metadata to the video.
+// Unknown durations are read from the file once downloaded.
+func applyVideoInfo(video *models.Video, info ytdlp.VideoInfo) {
+	if info.Title != "" {
+		video.Title = info.Title
+	}
+
+	if info.Duration > 0 {
+		video.Duration = formatDuration(info.Duration)
+	}
+
+	video.Uploader = info.Uploader
+	video.Channel = info.Channel
+	video.UploadDate = info.UploadDate
+	video.Description = info.Description
+	video.Tags = info.Tags
+	video.ViewCount = info.ViewCount
+	video.WebpageUrl = info.WebpageUrl
+
+	// Set once, the key the video was deduplicated with does not change
+	if video.SourceID == "" && info.ExtractorKey != "" && info.ID != "" {
+		video.ExtractorKey = info.ExtractorKey
+		video.SourceID = info.ID
+	}
+}
+
+// Playlist names are unique, a number is added 
+// to the name if it is already taken
+func createPlaylistWithUniqueName(repo repository.PlaylistRepository, name string) (int64, error) {
+	if name == "" {
+		name = "Playlist"
+	}
+
+	currentDate := time.Now().Format("2006-01-02 15:04:05")
+	id, err := repo.Create(name, currentDate)
+
+	for i := 2; err != nil && i < 100; i++ {
+		id, err = repo.Create(fmt.Sprintf("%s (%d)", name, i), currentDate)
+	}
+
+	return id, err
 }
 
 func validateNewVideoForm(data NewVideoFormData, r repository.PlaylistRepository) []string {
 	var errors []string 
 
 	if data.PlaylistID < 1 {
-		errors = append(errors, "Invalid playlist")
+		// A playlist is created for playlist/channel urls
+		if !(data.Source == "ytdlp" && data.CreatePlaylist) {
+			errors = append(errors, "Invalid playlist")
+		}
 	} else {
 		_, err := r.Get(fmt.Sprint(data.PlaylistID))
 		if err != nil {
@@ -451,12 +785,48 @@ func validateNewVideoForm(data NewVideoFormData, r repository.PlaylistRepository
 				errors = append(errors, "Folder does not exist")
 			}
 		}
+
+		for _, pattern := range append(data.Include, data.Exclude...) {
+			if files.ValidateGlob(pattern) != nil {
+				errors = append(errors, "Invalid pattern: " + pattern)
+			}
+		}
 	} else if data.Source == "ytdlp" {
 		if data.URL == "" {
 			errors = append(errors, "URL cannot be blank")
 		} else if !isValidURL(data.URL) {
 			errors = append(errors, "Invalid URL")
 		}
+
+		if data.AudioOnly && data.AudioFormat != "" && !ytdlp.IsAudioFormat(data.AudioFormat) {
+			errors = append(errors, "Invalid audio format")
+		}
+
+		if data.AudioOnly && downloader.IsDirectMediaUrl(data.URL) {
+			errors = append(errors, "Audio only is not supported for direct media links")
+		}
+
+		if data.Live != "" && !ytdlp.IsLiveMode(data.Live) {
+			errors = append(errors, "Invalid live mode")
+		}
+
+		if data.Live != "" && (data.AudioOnly || downloader.IsDirectMediaUrl(data.URL)) {
+			errors = append(errors, "Live recordings cannot be audio only or direct media links")
+		}
+
+		if data.RateLimit < 0 {
+			errors = append(errors, "Rate limit cannot be negative")
+		}
+
+		if data.SponsorBlockMode != "" && data.SponsorBlockMode != sponsorBlockNone && !ytdlp.IsSponsorBlockMode(data.SponsorBlockMode) {
+			errors = append(errors, "Invalid SponsorBlock mode")
+		}
+
+		for _, language := range data.SubtitleLanguages {
+			if !isValidSubtitleLanguage(language) {
+				errors = append(errors, "Invalid subtitle language: " + language)
+			}
+		}
 	} else {
 		errors = append(errors, "Form type not disk, or ydlp")
 	}
@@ -469,138 +839,191 @@ func isValidURL(str string) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
-func loadVideosFromDisk(folderPath string, playlistID string, playlistVideoRepo repository.PlaylistVideoRepository, videoRepo repository.VideoRepository, rootFolderPath string) error {
-	paths, err := getFilesWithExtensions(folderPath, []string{".mp4", ".webm"})
+// Imports a file found in the form's folder if ffprobe 
+// finds a playable video stream in it, whatever its extension
+func importFileFromDisk(foundFile files.FoundFile, playlistID string, repositories *repository.Repositories, rootFolderPath string) imports.FileResult {
+	videoRepo := repositories.VideoRepo
+	playlistVideoRepo := repositories.PlaylistVideoRepo
+	path := foundFile.Path
 
-	if err != nil {
-		log.Println("Error getting files", err)
-		return err
+	failed := func(reason string, err error) imports.FileResult {
+		log.Println(reason, path, err)
+		return imports.FileResult{Status: imports.FileFailed, Reason: reason}
 	}
 
-	if len(paths) == 0 {
-		return errors.New("folder does not contain .mp4 or .webm files")
-	}
+	probe, err := probeVideo(path)
 
-	for _, path := range paths {
-    	ext := filepath.Ext(path)
-		if ext != ".webm" && ext != ".mp4" {
-			continue
-		}
+	if err != nil || !probe.isPlayable() {
+		return imports.FileResult{Status: imports.FileSkipped}
+	}
 
-		// Get the md5 checksum
-		checksum, err := computeChecksum(path)
+	// Get the md5 checksum
+	checksum, err := computeChecksum(path)
 
-    	if err != nil {
-			log.Println("Error creating md5 checksum", err)
-			continue
-		}
+	if err != nil {
+		return failed("Error creating md5 checksum", err)
+	}
 
-		existingVideo, _ := videoRepo.GetBy(checksum, "md5_checksum")
+	existingVideo, _ := videoRepo.GetBy(checksum, "md5_checksum")
 
-		// If file already exists in DB skip it
-		if (existingVideo != nil) {
-			log.Println("Video already exists, skipping video:", path)
-			continue
-		}
+	// If file already exists in DB skip it
+	if (existingVideo != nil) {
+		log.Println("Video already exists, skipping video:", path)
+		return imports.FileResult{Status: imports.FileDuplicate, VideoID: existingVideo.ID}
+	}
 
-		// Create file_id
-		fileID, err := generateFileID()
-    	if err != nil {
-			log.Println("Error generating fileID", path)
-			continue
-		}
+	// Create file_id
+	fileID, err := generateFileID()
+	if err != nil {
+		return failed("Error generating fileID", err)
+	}
 
-		// Insert Video into DB
-		video := models.Video{}
-		video.DownloadComplete = true 
-		video.DownloadDate = time.Now().Format("2006-01-02 15:04:05")
-		video.Title = strings.TrimSuffix(filepath.Base(path), ext)
-		video.FileFormat = strings.TrimPrefix(ext, ".")
-		video.Md5Checksum = checksum
-		video.FileID = fileID
+	// Insert Video into DB
+	video := models.Video{}
+	video.DownloadComplete = true 
+	video.DownloadDate = time.Now().Format("2006-01-02 15:04:05")
+	video.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	video.FileFormat = importedFileFormat(path, probe)
+	video.Md5Checksum = checksum
+	video.FileID = fileID
+	video.SourceFolder = foundFile.Folder
+	video.Duration = formatDuration(probe.duration())
+	
+	videoID, err := videoRepo.Create(video)
 
-        duration, err := getVideoDuration(path)
+	if err != nil {
+		return failed("Error inserting video into videos table", err)
+	}
 
-		if err == nil {
-			video.Duration = duration
-		} else {
-			log.Println("Error getting duration for video:", path, "error:", err)
-		}
-		
-		videoID, err := videoRepo.Create(video)
+	// Insert playlistVideo item
+	_, err = playlistVideoRepo.Create(playlistID, fmt.Sprint(videoID))
 
-		if err != nil {
-			log.Println("Error inserting video into videos table", err)
-			continue
-		}
+	if err != nil {
+		videoRepo.Delete(fmt.Sprint(videoID))
+		return failed("Error inserting playlistVideo entry into db", err)
+	}
 
-		// Insert playlistVideo item
-		_, err = playlistVideoRepo.Create(playlistID, fmt.Sprint(videoID))
+	// Create folders to store the file
+	destinationFolderPath, err := files.CreateFileFolders(rootFolderPath, fileID)
+	if (err != nil)  {
+		videoRepo.Delete(fmt.Sprint(videoID))
+		return failed("Error creating folders for video file", err)
+	}
 
-		if err != nil {
-			log.Println("Error inserting plalistVideo entry into db", err)
-			videoRepo.Delete(fmt.Sprint(videoID))
-			continue
-		}
+	// Copy file to new destination
+	err = files.CopyFile(path, filepath.Join(destinationFolderPath, fileID + "." + video.FileFormat))
+	if (err != nil)  {
+		videoRepo.Delete(fmt.Sprint(videoID))
+		return failed("Error copying file to new folder", err)
+	}
 
-		// Create folders to store the file
-		destinationFolderPath, err := files.CreateFileFolders(rootFolderPath, fileID)
-		if (err != nil)  {
-      		log.Println("Error creating folders for video file", err)
-			videoRepo.Delete(fmt.Sprint(videoID))
-			continue
-		}
+	// Create a video thumbnail and save to destination
+	err = extractThumbnail(path, filepath.Join(destinationFolderPath, fileID + ".jpg"))
+	if (err != nil)  {
+		log.Println("Error creating video thumbnail", err)
+	}
 
-		// Copy file to new destination
-		err = files.CopyFile(path, filepath.Join(destinationFolderPath, fileID + ext))
-    	if (err != nil)  {
-      		log.Println("Error copying file to new folder", err)
-			videoRepo.Delete(fmt.Sprint(videoID))
-			continue
-		}
+	// Copy .srt/.vtt files next to the video
+	video.ID = videoID
+	importSidecarSubtitles(path, video, rootFolderPath, repositories.SubtitleRepo)
 
-		// Create a video thumbnail and save to destination
-		extractThumbnail(path, filepath.Join(destinationFolderPath, fileID + ".jpg"))
-    	if (err != nil)  {
-      		log.Println("Error creating video thumbnail", err)
-		}
+	saveFileChapters(videoID, path, repositories.ChapterRepo)
 
-		// Write to websocket so client can refresh
-		ws.CurrentHub.WriteToClients(ws.WebsocketMessage{Type: string(ws.VideoDownloadSuccess)})
-	}
+	// Write to websocket so client can refresh
+	ws.CurrentHub.WriteToClients(ws.WebsocketMessage{Type: string(ws.VideoDownloadSuccess)})
 
-	return nil
+	return imports.FileResult{Status: imports.FileImported, VideoID: videoID}
 }
 
 // Downloads video from yt-dlp
-func LoadVideoWithYtdlp(video models.Video, playlistVideoRepository repository.PlaylistVideoRepository, videoRepository repository.VideoRepository, rootFolderPath string, tempFolderPath string, dm *downloadManager.DownloadManager) error {
+func LoadVideoWithYtdlp(video models.Video, repositories *repository.Repositories, rootFolderPath string, tempFolderPath string, dm *downloadManager.DownloadManager) error {
 	downloadImgPath   := filepath.Join(tempFolderPath, video.FileID)
-	downloadVideoPathWithExt := filepath.Join(tempFolderPath, video.FileID+".mp4") 
+	downloadVideoPathWithExt := filepath.Join(tempFolderPath, video.FileID+"."+video.FileFormat) 
 	downloadImgPathWithExt   := filepath.Join(tempFolderPath, video.FileID+".jpg")
 
   	download, _ := dm.AddNewDownload(video)
 
+	// Final failure, the video and its files are removed
 	onDownloadError := func(err error) {
-		download.IsError = true
-		download.IsComplete = false
-		playlistVideoRepository.OnDeleteVideo(strconv.FormatInt(video.ID, 10))
-		videoRepository.Delete(strconv.FormatInt(video.ID, 10))
+		log.Println("Download failed:", video.Url, err)
+		dm.FailDownload(download, err)
+		repositories.PlaylistVideoRepo.OnDeleteVideo(strconv.FormatInt(video.ID, 10))
+		repositories.SubtitleRepo.OnDeleteVideo(strconv.FormatInt(video.ID, 10))
+		repositories.ChapterRepo.OnDeleteVideo(strconv.FormatInt(video.ID, 10))
+		repositories.SponsorBlockSegmentRepo.OnDeleteVideo(strconv.FormatInt(video.ID, 10))
+		repositories.VideoRepo.Delete(strconv.FormatInt(video.ID, 10))
 		files.DeleteFilesWithPrefix(rootFolderPath, video.FileID)
+
+		ws.CurrentHub.WriteToClients(ws.WebsocketMessage{
+			Type: string(ws.VideoDownloadFail),
+			Payload: ws.VideoDownloadFailPayload{
+				VideoID: video.ID,
+				Title:   video.Title,
+				Url:     video.Url,
+				Reason:  download.ErrorReason,
+				Message: download.ErrorMsg,
+			},
+		})
+	}
+
+	// yt-dlp exited with an error, retry while there are attempts left
+	onDownloadRunError := func(err error) {
+		if download.IsCancelled || download.IsPaused {
+			return
+		}
+
+		if dm.RetryDownload(download, err) {
+			return
+		}
+
+		onDownloadError(err)
 	}
 
 	onDownloadComplete := func() {
+		// Videos from playlists and channels are created 
+		// with only the title and duration
+		if video.WebpageUrl == "" {
+			info, err := ytdlp.GetVideoInfo(video.Url)
+			if err == nil {
+				applyVideoInfo(&video, *info)
+				saveYtdlpChapters(video.ID, info.Chapters, repositories.ChapterRepo)
+
+				// Queued under another url of a video added in the meantime,
+				// the library keeps one copy of each video
+				if existing, _ := repositories.VideoRepo.GetBySource(video.ExtractorKey, video.SourceID); existing != nil && existing.ID != video.ID {
+					onDownloadError(fmt.Errorf("video is already in the library as video %d", existing.ID))
+					return
+				}
+			} else {
+				log.Println("Error getting video info:", err)
+			}
+		}
+
+		saveSponsorBlockInfo(&video, ytdlp.SponsorBlockInfoPath(downloadVideoPathWithExt), downloadVideoPathWithExt, repositories)
+
+		// The metadata of a live stream has no duration,
+		// it is the length of the recording
+		if video.LiveMode != "" {
+			video.Duration = ""
+		}
+
 		if (video.Duration == "") {
 			d, err := getVideoDuration(downloadVideoPathWithExt)
 			if (err == nil) {
 				video.Duration = d 
-				videoRepository.Update(video)
+				repositories.VideoRepo.Update(video)
 			} else {
 				log.Println("Error extracting duration:", err)
 				onDownloadError(err)
+				return
 			}
 		}
 
-		thumbnail_extract_err := ytdlp.DownloadVideoThumbnail(video.Url, downloadImgPath)
+		// Direct media links have no thumbnail to download
+		thumbnail_extract_err := errors.New("no thumbnail")
+		if !downloader.IsDirectMediaUrl(video.Url) {
+			thumbnail_extract_err = ytdlp.DownloadVideoThumbnail(video.Url, downloadImgPath)
+		}
 
 		// If img fetch unsuccessful, use FFMPEG
 		if thumbnail_extract_err != nil {
@@ -630,42 +1053,54 @@ func LoadVideoWithYtdlp(video models.Video, playlistVideoRepository repository.P
 			return 
 		}
 			
+		// Audio without a thumbnail has no frame to extract one from
+		_, statErr := os.Stat(downloadImgPathWithExt)
+		skipThumbnail := statErr != nil && ytdlp.IsAudioFormat(video.FileFormat)
+
 		// Move image file from temp folder to the new folder
 		newImageFilePath := filepath.Join(folderPath, imgBaseName)
-		err = files.MoveFile(downloadImgPathWithExt, newImageFilePath)
-		if err != nil {
-			log.Println("Error moving image file from temp folder", err)
-			onDownloadError(err)
-			return
+		if !skipThumbnail {
+			err = files.MoveFile(downloadImgPathWithExt, newImageFilePath)
+			if err != nil {
+				log.Println("Error moving image file from temp folder", err)
+				onDownloadError(err)
+				return
+			}
+		}
+
+		downloadYtdlpSubtitles(video, tempFolderPath, rootFolderPath, repositories.SubtitleRepo)
+
+		// Chapters from the yt-dlp metadata are saved when the video is created
+		saveFileChapters(video.ID, newVideoFilePath, repositories.ChapterRepo)
+
+		if info, err := os.Stat(newVideoFilePath); err == nil {
+			download.BytesDownloaded = info.Size()
+			download.TotalBytes = info.Size()
 		}
 
 		// Update video 
-		err = updateVideoOnDownloadSuccess(videoRepository, video, newVideoFilePath)
+		err = updateVideoOnDownloadSuccess(repositories.VideoRepo, video, newVideoFilePath)
 		if err != nil {
 			log.Println("Error while updating video:", err)
 			onDownloadError(err)
 			return
 		}
 
-		download.OnComplete()
+		dm.CompleteDownload(download)
 	}
 
-	onReadOutput := func(progress uint, speed string) {  
-		download.Progress = progress
-		download.Speed = speed
+	download.Request = downloader.Request{
+		Url: video.Url,
+		Format: video.VideoFormat.String,
+		FileFormat: video.FileFormat,
+		FilePath: downloadVideoPathWithExt,
+		SponsorBlock: video.SponsorBlockMode,
+		Live: video.LiveMode,
 	}
 
-	cmd := ytdlp.CreateDownloadCommand(
-		video.Url, 
-		video.VideoFormat.String, 
-		downloadVideoPathWithExt,
-	)
-
-	// Run download process
-	go ytdlp.RunDownload(cmd, onReadOutput, onDownloadComplete, onDownloadError)
-
-	// Listen for events to cancel download process. 
-	go download.OnStartDownload(cmd)
+	// Queue the download, it starts once the
+	// download manager has a free slot
+	dm.Enqueue(download, onDownloadComplete, onDownloadRunError)
 
 	return nil
 }	
@@ -729,6 +1164,11 @@ func getVideoDuration(path string) (duration string, err error) {
        return "", err
    }
 
+   return formatDuration(durationInSeconds), nil
+}
+
+// Formats seconds as h:mm:ss, m:ss or s
+func formatDuration(durationInSeconds float64) string {
    // Convert the duration in seconds to a time.Duration
    durationTime := time.Duration(durationInSeconds * float64(time.Second))
 
@@ -738,11 +1178,11 @@ func getVideoDuration(path string) (duration string, err error) {
    seconds := int(durationTime.Seconds()) % 60
 
    if hours > 0 {
-       return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds), nil
+       return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
    } else if minutes > 0 {
-       return fmt.Sprintf("%d:%02d", minutes, seconds), nil
+       return fmt.Sprintf("%d:%02d", minutes, seconds)
    } else {
-       return fmt.Sprintf("%d", seconds), nil
+       return fmt.Sprintf("%d", seconds)
    } 
 }
 
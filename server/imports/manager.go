@@ -0,0 +1,263 @@
+package imports
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+	"vidviewer/files"
+	ws "vidviewer/websocket"
+)
+
+// Status of a job
+const (
+	StatusRunning   = "running"
+	StatusComplete  = "complete"
+	StatusCancelled = "cancelled"
+)
+
+// Status of each file of a job
+const (
+	FileImported  = "imported"
+	FileDuplicate = "duplicate" // a file with the same checksum is in the library
+	FileSkipped   = "skipped"   // not a video
+	FileFailed    = "failed"
+)
+
+// Finished jobs kept for GET /imports/{id}, the oldest are dropped first
+const maxFinishedJobs = 20
+
+// Result of importing a file
+type FileResult struct {
+	Path    string `json:"path"`
+	Status  string `json:"status"`
+	VideoID int64  `json:"video_id,omitempty"`
+	Reason  string `json:"reason,omitempty"` // why the file failed
+}
+
+// Imports the found file, called for each file of a job
+type ImportFunc func(file files.FoundFile) FileResult
+
+// An import of the files of a folder, running in the background
+type Job struct {
+	ID          string       `json:"id"`
+	Folder      string       `json:"folder"`
+	Status      string       `json:"status"`
+	Total       int          `json:"total"`
+	Processed   int          `json:"processed"`
+	Imported    int          `json:"imported"`
+	Duplicates  int          `json:"duplicates"`
+	Skipped     int          `json:"skipped"`
+	Failed      int          `json:"failed"`
+	Failures    []FileResult `json:"failures"`
+	TimeStarted int64        `json:"time_started"`
+	// 0 while running
+	TimeCompleted int64 `json:"time_completed"`
+	cancel        context.CancelFunc
+}
+
+// Payload of the ImportProgress message, sent after each file
+// and once the job is finished, with File nil
+type ProgressPayload struct {
+	Job  Job         `json:"job"`
+	File *FileResult `json:"file"`
+}
+
+// Runs the import jobs and keeps their status in memory
+type Manager struct {
+	jobs  map[string]*Job
+	order []string // job ids, oldest first
+	mutex sync.Mutex
+	wg    sync.WaitGroup
+}
+
+func NewManager() *Manager {
+	return &Manager{jobs: map[string]*Job{}}
+}
+
+func newJobID() (string, error) {
+	bytes := make([]byte, 8)
+
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
+
+// Starts importing the files in the background and returns the job
+func (m *Manager) Start(folder string, foundFiles []files.FoundFile, importFile ImportFunc) (Job, error) {
+	id, err := newJobID()
+
+	if err != nil {
+		return Job{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	job := &Job{
+		ID:          id,
+		Folder:      folder,
+		Status:      StatusRunning,
+		Total:       len(foundFiles),
+		Failures:    []FileResult{},
+		TimeStarted: time.Now().Unix(),
+		cancel:      cancel,
+	}
+
+	m.mutex.Lock()
+	m.jobs[id] = job
+	m.order = append(m.order, id)
+	m.pruneJobs()
+	snapshot := job.snapshot()
+	m.mutex.Unlock()
+
+	m.wg.Add(1)
+
+	go func() {
+		defer m.wg.Done()
+		m.run(ctx, job, foundFiles, importFile)
+	}()
+
+	return snapshot, nil
+}
+
+func (m *Manager) run(ctx context.Context, job *Job, foundFiles []files.FoundFile, importFile ImportFunc) {
+	defer job.cancel()
+
+	status := StatusComplete
+
+	for _, file := range foundFiles {
+		// The file being imported is finished first
+		if ctx.Err() != nil {
+			status = StatusCancelled
+			break
+		}
+
+		result := importFile(file)
+		result.Path = file.Path
+
+		m.mutex.Lock()
+		job.add(result)
+		snapshot := job.snapshot()
+		m.mutex.Unlock()
+
+		writeProgress(snapshot, &result)
+	}
+
+	m.mutex.Lock()
+	job.Status = status
+	job.TimeCompleted = time.Now().Unix()
+	snapshot := job.snapshot()
+	m.mutex.Unlock()
+
+	writeProgress(snapshot, nil)
+}
+
+func (job *Job) add(result FileResult) {
+	job.Processed++
+
+	switch result.Status {
+	case FileImported:
+		job.Imported++
+	case FileDuplicate:
+		job.Duplicates++
+	case FileSkipped:
+		job.Skipped++
+	default:
+		job.Failed++
+		job.Failures = append(job.Failures, result)
+	}
+}
+
+// Copy of the job that is safe to read without the mutex
+func (job *Job) snapshot() Job {
+	snapshot := *job
+	snapshot.Failures = append([]FileResult{}, job.Failures...)
+	snapshot.cancel = nil
+	return snapshot
+}
+
+func writeProgress(job Job, file *FileResult) {
+	ws.CurrentHub.WriteToClients(ws.WebsocketMessage{
+		Type:    string(ws.ImportProgress),
+		Payload: ProgressPayload{Job: job, File: file},
+	})
+}
+
+// Drops the oldest finished jobs, m.mutex must be held
+func (m *Manager) pruneJobs() {
+	finished := 0
+	for _, id := range m.order {
+		if m.jobs[id].Status != StatusRunning {
+			finished++
+		}
+	}
+
+	order := []string{}
+
+	for _, id := range m.order {
+		if finished > maxFinishedJobs && m.jobs[id].Status != StatusRunning {
+			delete(m.jobs, id)
+			finished--
+			continue
+		}
+		order = append(order, id)
+	}
+
+	m.order = order
+}
+
+// Returns false if there is no job with the id
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, ok := m.jobs[id]
+
+	if !ok {
+		return Job{}, false
+	}
+
+	return job.snapshot(), true
+}
+
+// Stops the job after the file being imported. Returns
+// false if there is no job with the id, finished jobs
+// are returned unchanged.
+func (m *Manager) Cancel(id string) (Job, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, ok := m.jobs[id]
+
+	if !ok {
+		return Job{}, false
+	}
+
+	job.cancel()
+
+	return job.snapshot(), true
+}
+
+// Cancels the running jobs and waits for them to stop, or the timeout
+func (m *Manager) Shutdown(timeout time.Duration) {
+	m.mutex.Lock()
+	for _, job := range m.jobs {
+		job.cancel()
+	}
+	m.mutex.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,116 @@
+package imports
+
+import (
+	"testing"
+	"time"
+	"vidviewer/files"
+)
+
+func waitForJob(t *testing.T, m *Manager, id string) Job {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		job, ok := m.Get(id)
+
+		if !ok {
+			t.Fatalf("Expected job %s to exist", id)
+		}
+
+		if job.Status != StatusRunning {
+			return job
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("Job %s did not finish", id)
+	return Job{}
+}
+
+func TestStart(t *testing.T) {
+	m := NewManager()
+
+	found := []files.FoundFile{
+		{Path: "a.mkv"},
+		{Path: "b.mp4"},
+		{Path: "c.txt"},
+		{Path: "d.mov"},
+	}
+
+	statuses := map[string]string{
+		"a.mkv": FileImported,
+		"b.mp4": FileDuplicate,
+		"c.txt": FileSkipped,
+		"d.mov": FileFailed,
+	}
+
+	job, err := m.Start("folder", found, func(file files.FoundFile) FileResult {
+		result := FileResult{Status: statuses[file.Path]}
+		if result.Status == FileFailed {
+			result.Reason = "Error copying file"
+		}
+		return result
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to start job: %s\n", err)
+	}
+
+	if job.ID == "" || job.Total != 4 {
+		t.Errorf("Expected a job with an id and 4 files, got %+v", job)
+	}
+
+	job = waitForJob(t, m, job.ID)
+
+	if job.Status != StatusComplete || job.Processed != 4 {
+		t.Errorf("Expected 4 processed files and a complete job, got %+v", job)
+	}
+
+	if job.Imported != 1 || job.Duplicates != 1 || job.Skipped != 1 || job.Failed != 1 {
+		t.Errorf("Unexpected counts %+v", job)
+	}
+
+	if len(job.Failures) != 1 || job.Failures[0].Path != "d.mov" || job.Failures[0].Reason == "" {
+		t.Errorf("Expected d.mov to be reported as failed with a reason, got %+v", job.Failures)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	m := NewManager()
+
+	found := []files.FoundFile{{Path: "a.mkv"}, {Path: "b.mkv"}, {Path: "c.mkv"}}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	job, err := m.Start("folder", found, func(file files.FoundFile) FileResult {
+		if file.Path == "a.mkv" {
+			close(started)
+			<-release
+		}
+		return FileResult{Status: FileImported}
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to start job: %s\n", err)
+	}
+
+	<-started
+
+	if _, ok := m.Cancel(job.ID); !ok {
+		t.Fatalf("Expected job %s to be cancelled", job.ID)
+	}
+
+	close(release)
+
+	job = waitForJob(t, m, job.ID)
+
+	// The file being imported is finished, the others are not started
+	if job.Status != StatusCancelled || job.Processed != 1 {
+		t.Errorf("Expected a cancelled job with 1 processed file, got %+v", job)
+	}
+
+	if _, ok := m.Cancel("unknown"); ok {
+		t.Errorf("Expected an unknown job not to be found")
+	}
+}
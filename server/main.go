@@ -1,97 +1,159 @@
-package main
-
-import (
-	"embed"
-	"flag"
-	"log"
-	"net/http"
-	"time"
-	"vidviewer/config"
-	"vidviewer/db"
-	"vidviewer/downloadManager"
-	"vidviewer/repository"
-	"vidviewer/routes"
-
-	"github.com/gorilla/handlers"
-
-	"github.com/golang-migrate/migrate/v4/source/iofs"
-	_ "modernc.org/sqlite"
-)
-
-// Files/directories embeded into build
-
-//go:embed build/*.html
-var htmlFiles embed.FS
-
-//go:embed build
-var assets embed.FS
-
-//go:embed migrations/*
-var migrations embed.FS
-
-// Passed with ldflags = go build -ldflags "-X main.myVar=myValue" 
-var serverPort string
-var clientPort string
-
-func main() {
-	log.SetFlags(log.Lshortfile | log.LstdFlags)
-
-    var mode string
-	flag.StringVar(&mode, "mode", "production", "Mode of application runtime")
-	flag.Parse()
-
-	isTestMode := mode == "test"
-
-	config.Initialize(isTestMode) 
-
-	// Check if the migrations directory exists in the embedded file system
-    _, err := migrations.ReadDir("migrations")
-
-    if err == nil { 
-		d, err := iofs.New(migrations, "migrations")
-		if err != nil {
-			log.Fatal(err)
-		} 
-		// Save the pointer to the embeded migrations driver 
-		db.SetEmbededMigrations(d)
-	}
-	
-	db.InitializeDB()
-
-	repositories := repository.NewRepositories()
-	dm := downloadManager.NewDownloadManager()
-	r := routes.Initialize(assets, htmlFiles, repositories, dm)
-
-	var srv *http.Server
-
-	if mode == "dev" {
-		credentials := handlers.AllowCredentials()
-		methods := handlers.AllowedMethods([]string{"GET", "POST", "PATCH", "DELETE", "PUT"})
-		headers := handlers.AllowedHeaders([]string{"Content-Type", "Authorization"})
-		origins := handlers.AllowedOrigins([]string{"http://localhost:" + clientPort})
-		corsHandler := handlers.CORS(credentials, methods, headers, origins)(r)
-
-		srv = &http.Server{
-			Addr:         ":" + serverPort,
-			Handler:      corsHandler,
-			IdleTimeout:  4 * time.Second,
-			ReadTimeout:  4 * time.Second,
-			WriteTimeout: 4 * time.Second,
-		}
-	} else {
-		srv = &http.Server {
-			Addr:         ":" + serverPort,
-			Handler:      r,
-			IdleTimeout:  4 * time.Second,
-			ReadTimeout:  4 * time.Second,
-			WriteTimeout: 4 * time.Second,
-		}
-	}	
-
-	log.Fatal(
-		srv.ListenAndServeTLS(
-			config.GetSSLCertPath(), 
-			config.GetSSlKeyPath(),
-		),
-	)
-}
+package main
+
+import (
+	"context"
+	"embed"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"vidviewer/availability"
+	"vidviewer/config"
+	"vidviewer/db"
+	"vidviewer/downloadManager"
+	"vidviewer/files"
+	apiHandlers "vidviewer/handlers"
+	"vidviewer/imports"
+	"vidviewer/models"
+	"vidviewer/repository"
+	"vidviewer/routes"
+	"vidviewer/subscriptions"
+	"vidviewer/ytdlp"
+
+	"github.com/gorilla/handlers"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "modernc.org/sqlite"
+)
+
+// Files/directories embeded into build
+
+//go:embed build/*.html
+var htmlFiles embed.FS
+
+//go:embed build
+var assets embed.FS
+
+//go:embed migrations/*
+var migrations embed.FS
+
+// Passed with ldflags = go build -ldflags "-X main.myVar=myValue" 
+var serverPort string
+var clientPort string
+
+// How long requests and downloads have to stop on shutdown
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	log.SetFlags(log.Lshortfile | log.LstdFlags)
+
+    var mode string
+	flag.StringVar(&mode, "mode", "production", "Mode of application runtime")
+	flag.Parse()
+
+	isTestMode := mode == "test"
+
+	config.Initialize(isTestMode) 
+
+	// Check if the migrations directory exists in the embedded file system
+    _, err := migrations.ReadDir("migrations")
+
+    if err == nil { 
+		d, err := iofs.New(migrations, "migrations")
+		if err != nil {
+			log.Fatal(err)
+		} 
+		// Save the pointer to the embeded migrations driver 
+		db.SetEmbededMigrations(d)
+	}
+	
+	db.InitializeDB()
+
+	repositories := repository.NewRepositories()
+	dm := downloadManager.NewDownloadManager()
+	im := imports.NewManager()
+
+	// Continue the downloads interrupted by the last shutdown
+	dm.Resume = func(video models.Video) error {
+		return apiHandlers.ResumeInterruptedDownload(video, repositories, dm)
+	}
+
+	// Queue the new videos of subscribed channels and playlists
+	poller := subscriptions.NewPoller(repositories, func(subscription models.Subscription, videos []ytdlp.FlatInfo) {
+		apiHandlers.QueueSubscriptionVideos(subscription, videos, repositories, dm)
+	})
+
+	// Re-probes the urls of downloaded videos to find removed ones
+	checker := availability.NewChecker(&repositories.VideoRepo)
+
+	// Pick the interrupted downloads back up before serving requests. 
+	// Without a root folder this is done by the first request once it is set.
+	if c := config.Load(); c.FolderPath != "" && files.Initialize(c.FolderPath) == nil {
+		repositories.SetDB(db.UpdateActiveConnection(files.GetDatabasePath(c.FolderPath)))
+		dm.ApplyConfig(c)
+		dm.Initialize(repositories)
+	}
+
+	r := routes.Initialize(assets, htmlFiles, repositories, dm, im, poller, checker)
+
+	var srv *http.Server
+
+	if mode == "dev" {
+		credentials := handlers.AllowCredentials()
+		methods := handlers.AllowedMethods([]string{"GET", "POST", "PATCH", "DELETE", "PUT"})
+		headers := handlers.AllowedHeaders([]string{"Content-Type", "Authorization"})
+		origins := handlers.AllowedOrigins([]string{"http://localhost:" + clientPort})
+		corsHandler := handlers.CORS(credentials, methods, headers, origins)(r)
+
+		srv = &http.Server{
+			Addr:         ":" + serverPort,
+			Handler:      corsHandler,
+			IdleTimeout:  4 * time.Second,
+			ReadTimeout:  4 * time.Second,
+			WriteTimeout: 4 * time.Second,
+		}
+	} else {
+		srv = &http.Server {
+			Addr:         ":" + serverPort,
+			Handler:      r,
+			IdleTimeout:  4 * time.Second,
+			ReadTimeout:  4 * time.Second,
+			WriteTimeout: 4 * time.Second,
+		}
+	}	
+
+	go func() {
+		err := srv.ListenAndServeTLS(
+			config.GetSSLCertPath(), 
+			config.GetSSlKeyPath(),
+		)
+
+		if err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for Ctrl-C or SIGTERM
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Println("shutting down")
+
+	// Stop accepting requests
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("Error shutting down server:", err)
+	}
+
+	// Stop yt-dlp and keep the partial downloads for the next start
+	dm.Shutdown(shutdownTimeout)
+
+	// The file being imported is finished, the others are left
+	im.Shutdown(shutdownTimeout)
+}
@@ -9,6 +9,8 @@ import (
 
 // Checks if ffmpeg and yt-dlp installed on system.
 // Writes error message to websocket if not found.
+// yt-dlp is only needed for some requests (direct media 
+// links are downloaded without it), handlers check for it.
 func FfmpegYtdlpMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// If its websocket or config request let it pass for initialization
@@ -30,9 +32,6 @@ func FfmpegYtdlpMiddleware(next http.Handler) http.Handler {
 		_, err = exec.LookPath("yt-dlp")
         if (err != nil) {
 			ws.GetHub().WriteToClients(ws.WebsocketMessage{Type: string(ws.YtdlpNotFound)})
-            error := errors.YtdlpNotFoundError()
-			http.Error(w, error.Error(), error.StatusCode)
-			return
 		}
 
 		next.ServeHTTP(w, r)
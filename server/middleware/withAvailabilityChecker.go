@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"vidviewer/availability"
+)
+
+// Starts the availability checker once the database is available
+func WithAvailabilityChecker(checker *availability.Checker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/websocket" || r.URL.Path == "/config" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			checker.Start()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"vidviewer/config"
 	"vidviewer/downloadManager"
 	"vidviewer/repository"
 )
@@ -16,12 +17,15 @@ func WithDownloadManagerMiddleware(dm *downloadManager.DownloadManager) func(htt
                 next.ServeHTTP(w, r)
                 return
             }
-	        videoRepo := r.Context().Value(RepositoryKey).(*repository.Repositories).VideoRepo
-			
-			// Initialize the download manager
-			if (!dm.IsInitialized) {
-				dm.Initialize(videoRepo)
-			}
+	        repositories := r.Context().Value(RepositoryKey).(*repository.Repositories)
+
+			// Pick up changes to the download settings from config
+			dm.ApplyConfig(r.Context().Value(ConfigKey).(config.Config))
+
+			// main initializes the download manager on start, unless 
+			// the root folder was not set yet. Does nothing if it did.
+			dm.Initialize(repositories)
+
 			r = r.WithContext(context.WithValue(r.Context(), DownloadManagerKey, dm))
 			next.ServeHTTP(w, r)
 		})
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"vidviewer/imports"
+)
+
+const ImportManagerKey MiddleWareKey = "ImportManagerKey"
+
+// Passes the import manager to handlers via router context
+func WithImportManager(im *imports.Manager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), ImportManagerKey, im))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
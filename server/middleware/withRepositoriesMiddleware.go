@@ -20,9 +20,7 @@ func WithRepositories(repositories *repository.Repositories) func(http.Handler)
             // Update repositories with current db
 	        sql := r.Context().Value(DBKey).(*sql.DB)
 
-            repositories.PlaylistRepo.SetDB(sql)
-            repositories.VideoRepo.SetDB(sql)
-            repositories.PlaylistVideoRepo.SetDB(sql)
+            repositories.SetDB(sql)
 
             // Add the repositories and database connection to the request context
             ctx := context.WithValue(r.Context(), RepositoryKey, repositories)
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"vidviewer/subscriptions"
+)
+
+const SubscriptionPollerKey MiddleWareKey = "SubscriptionPollerKey"
+
+// Starts the subscription poller once the database 
+// is available and passes it to handlers via router context
+func WithSubscriptionPoller(poller *subscriptions.Poller) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.URL.Path == "/websocket" || r.URL.Path == "/config") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			poller.Start()
+
+			r = r.WithContext(context.WithValue(r.Context(), SubscriptionPollerKey, poller))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,7 @@
+ALTER TABLE videos ADD COLUMN uploader TEXT DEFAULT '';
+ALTER TABLE videos ADD COLUMN channel TEXT DEFAULT '';
+ALTER TABLE videos ADD COLUMN upload_date TEXT DEFAULT '';
+ALTER TABLE videos ADD COLUMN description TEXT DEFAULT '';
+ALTER TABLE videos ADD COLUMN tags TEXT DEFAULT '[]';
+ALTER TABLE videos ADD COLUMN view_count INTEGER DEFAULT 0;
+ALTER TABLE videos ADD COLUMN webpage_url TEXT DEFAULT '';
@@ -0,0 +1,7 @@
+ALTER TABLE videos DROP COLUMN uploader;
+ALTER TABLE videos DROP COLUMN channel;
+ALTER TABLE videos DROP COLUMN upload_date;
+ALTER TABLE videos DROP COLUMN description;
+ALTER TABLE videos DROP COLUMN tags;
+ALTER TABLE videos DROP COLUMN view_count;
+ALTER TABLE videos DROP COLUMN webpage_url;
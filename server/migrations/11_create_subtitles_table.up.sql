@@ -0,0 +1,11 @@
+ALTER TABLE videos ADD COLUMN subtitle_languages TEXT DEFAULT '[]';
+ALTER TABLE videos ADD COLUMN auto_subtitles BOOLEAN DEFAULT 0;
+
+CREATE TABLE subtitles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    video_id INTEGER,
+    language TEXT,
+    auto_generated BOOLEAN DEFAULT 0,
+    FOREIGN KEY (video_id) REFERENCES videos(id),
+    UNIQUE (video_id, language)
+);
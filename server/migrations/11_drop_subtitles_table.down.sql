@@ -0,0 +1,4 @@
+DROP TABLE subtitles;
+
+ALTER TABLE videos DROP COLUMN subtitle_languages;
+ALTER TABLE videos DROP COLUMN auto_subtitles;
@@ -0,0 +1,10 @@
+CREATE TABLE chapters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    video_id INTEGER,
+    title TEXT,
+    start_time REAL DEFAULT 0,
+    end_time REAL DEFAULT 0,
+    FOREIGN KEY (video_id) REFERENCES videos(id)
+);
+
+CREATE INDEX idx_chapters_video_id ON chapters (video_id);
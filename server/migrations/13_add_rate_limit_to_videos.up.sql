@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN rate_limit INTEGER DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE downloads ADD COLUMN error_reason TEXT DEFAULT '';
@@ -0,0 +1,13 @@
+ALTER TABLE videos ADD COLUMN sponsorblock_mode TEXT DEFAULT '';
+
+CREATE TABLE sponsorblock_segments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    video_id INTEGER,
+    category TEXT,
+    action TEXT,
+    start_time REAL DEFAULT 0,
+    end_time REAL DEFAULT 0,
+    FOREIGN KEY (video_id) REFERENCES videos(id)
+);
+
+CREATE INDEX idx_sponsorblock_segments_video_id ON sponsorblock_segments (video_id);
@@ -0,0 +1,3 @@
+DROP TABLE sponsorblock_segments;
+
+ALTER TABLE videos DROP COLUMN sponsorblock_mode;
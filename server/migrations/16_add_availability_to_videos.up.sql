@@ -0,0 +1,2 @@
+ALTER TABLE videos ADD COLUMN availability TEXT DEFAULT '';
+ALTER TABLE videos ADD COLUMN availability_checked_at INTEGER DEFAULT 0;
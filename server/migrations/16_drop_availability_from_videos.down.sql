@@ -0,0 +1,2 @@
+ALTER TABLE videos DROP COLUMN availability_checked_at;
+ALTER TABLE videos DROP COLUMN availability;
@@ -0,0 +1,3 @@
+ALTER TABLE videos ADD COLUMN extractor_key TEXT DEFAULT '';
+ALTER TABLE videos ADD COLUMN source_id TEXT DEFAULT '';
+CREATE UNIQUE INDEX videos_source_index ON videos (extractor_key, source_id) WHERE source_id != '';
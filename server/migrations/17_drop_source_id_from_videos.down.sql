@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS videos_source_index;
+ALTER TABLE videos DROP COLUMN source_id;
+ALTER TABLE videos DROP COLUMN extractor_key;
@@ -0,0 +1,7 @@
+CREATE TABLE archive_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    extractor TEXT,
+    source_id TEXT,
+    created_at INTEGER DEFAULT 0,
+    UNIQUE (extractor, source_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN live_mode TEXT DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN source_folder TEXT DEFAULT '';
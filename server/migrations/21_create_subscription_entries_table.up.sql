@@ -0,0 +1,9 @@
+CREATE TABLE subscription_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    subscription_id INTEGER,
+    url TEXT,
+    extractor_key TEXT DEFAULT '',
+    source_id TEXT DEFAULT '',
+    UNIQUE (subscription_id, url),
+    FOREIGN KEY (subscription_id) REFERENCES subscriptions(id)
+);
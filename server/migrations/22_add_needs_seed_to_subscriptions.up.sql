@@ -0,0 +1 @@
+ALTER TABLE subscriptions ADD COLUMN needs_seed INTEGER DEFAULT 0;
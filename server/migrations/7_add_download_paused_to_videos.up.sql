@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN download_paused BOOLEAN DEFAULT 0;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE videos DROP COLUMN download_paused;
\ No newline at end of file
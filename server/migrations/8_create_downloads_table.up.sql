@@ -0,0 +1,17 @@
+CREATE TABLE downloads (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    video_id INTEGER,
+    url TEXT,
+    title TEXT,
+    format TEXT,
+    status TEXT,
+    error TEXT,
+    time_started INTEGER DEFAULT 0,
+    time_completed INTEGER DEFAULT 0,
+    bytes_downloaded INTEGER DEFAULT 0,
+    total_bytes INTEGER DEFAULT 0,
+    average_speed REAL DEFAULT 0
+);
+
+CREATE INDEX idx_downloads_video_id ON downloads (video_id);
+CREATE INDEX idx_downloads_status ON downloads (status);
@@ -0,0 +1 @@
+DROP TABLE downloads
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE subscriptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    url TEXT UNIQUE,
+    name TEXT,
+    playlist_id INTEGER,
+    format TEXT,
+    interval_minutes INTEGER,
+    last_checked INTEGER DEFAULT 0,
+    created_at INTEGER DEFAULT 0,
+    FOREIGN KEY (playlist_id) REFERENCES playlists(id)
+);
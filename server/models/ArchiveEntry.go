@@ -0,0 +1,10 @@
+package models
+
+// A video from a yt-dlp --download-archive file, "<extractor> <id>".
+// Extractor is the lowercased yt-dlp extractor key, eg. "youtube"
+type ArchiveEntry struct {
+	ID        int64  `json:"id"`
+	Extractor string `json:"extractor"`
+	SourceID  string `json:"source_id"`
+	CreatedAt int64  `json:"created_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// Start and end times are in seconds
+type Chapter struct {
+	ID        int64   `json:"id"`
+	VideoID   int64   `json:"video_id"`
+	Title     string  `json:"title"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
@@ -0,0 +1,48 @@
+package models
+
+// Download statuses
+const (
+	DownloadStatusQueued      = "queued"
+	DownloadStatusDownloading = "downloading"
+	DownloadStatusPaused      = "paused"
+	DownloadStatusRetrying    = "retrying"
+	DownloadStatusComplete    = "complete"
+	DownloadStatusCancelled   = "cancelled"
+	DownloadStatusFailed      = "failed"
+)
+
+// A row in the downloads (history) table.
+// Times are unix timestamps, average speed is in bytes per second
+type Download struct {
+	ID              int64   `json:"id"`
+	VideoID         int64   `json:"video_id"`
+	Url             string  `json:"url"`
+	Title           string  `json:"title"`
+	Format          string  `json:"format"`
+	Status          string  `json:"status"`
+	Error           string  `json:"error"`
+	ErrorReason     string  `json:"error_reason"` // see the reasons in vidviewer/errors
+	TimeStarted     int64   `json:"time_started"`
+	TimeCompleted   int64   `json:"time_completed"`
+	BytesDownloaded int64   `json:"bytes_downloaded"`
+	TotalBytes      int64   `json:"total_bytes"`
+	AverageSpeed    float64 `json:"average_speed"`
+}
+
+// Percentage of the bytes downloaded, 0 if the total is unknown
+func (d Download) Percent() uint {
+	if d.TotalBytes <= 0 {
+		return 0
+	}
+
+	if d.BytesDownloaded >= d.TotalBytes {
+		return 100
+	}
+
+	return uint(d.BytesDownloaded * 100 / d.TotalBytes)
+}
+
+// Returns true if the download will not change anymore
+func (d Download) IsFinished() bool {
+	return d.Status == DownloadStatusComplete || d.Status == DownloadStatusCancelled || d.Status == DownloadStatusFailed
+}
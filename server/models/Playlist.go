@@ -1,7 +1,7 @@
-package models
-
-type Playlist struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
-	Date string `json:"date"`
+package models
+
+type Playlist struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Date string `json:"date"`
 }
\ No newline at end of file
@@ -0,0 +1,33 @@
+package models
+
+// SponsorBlock categories
+const (
+	SponsorBlockSponsor       = "sponsor"
+	SponsorBlockSelfPromo     = "selfpromo"
+	SponsorBlockInteraction   = "interaction"
+	SponsorBlockIntro         = "intro"
+	SponsorBlockOutro         = "outro"
+	SponsorBlockPreview       = "preview"
+	SponsorBlockFiller        = "filler"
+	SponsorBlockMusicOfftopic = "music_offtopic"
+	SponsorBlockHighlight     = "poi_highlight"
+)
+
+// What the player does with a segment
+const (
+	SponsorBlockActionSkip = "skip"
+	SponsorBlockActionMute = "mute"
+	// Highlight, a single point to jump to (start and end time are equal)
+	SponsorBlockActionPoi = "poi"
+)
+
+// A SponsorBlock segment of a video downloaded in "mark" mode.
+// Start and end times are in seconds
+type SponsorBlockSegment struct {
+	ID        int64   `json:"id"`
+	VideoID   int64   `json:"video_id"`
+	Category  string  `json:"category"`
+	Action    string  `json:"action"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
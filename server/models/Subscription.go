@@ -0,0 +1,26 @@
+package models
+
+// A channel or playlist url that is checked for new videos.
+// Times are unix timestamps
+type Subscription struct {
+	ID              int64  `json:"id"`
+	Url             string `json:"url"`
+	Name            string `json:"name"`
+	PlaylistID      int64  `json:"playlist_id"`
+	Format          string `json:"format"`
+	IntervalMinutes int    `json:"interval_minutes"`
+	LastChecked     int64  `json:"last_checked"`
+	CreatedAt       int64  `json:"created_at"`
+	// The videos already in the channel or playlist are not recorded
+	// yet, the poller seeds the subscription before checking it
+	NeedsSeed bool `json:"needs_seed"`
+}
+
+// A video that was in the channel or playlist when the subscription
+// was created, or that the poller queued, it is not queued again
+type SubscriptionEntry struct {
+	SubscriptionID int64
+	Url            string
+	ExtractorKey   string
+	SourceID       string
+}
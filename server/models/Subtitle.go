@@ -0,0 +1,9 @@
+package models
+
+// A WebVTT subtitle track, stored next to the video file
+type Subtitle struct {
+	ID            int64  `json:"id"`
+	VideoID       int64  `json:"video_id"`
+	Language      string `json:"language"`
+	AutoGenerated bool   `json:"auto_generated"`
+}
@@ -1,16 +1,55 @@
-package models
-
-import "database/sql"
-
-type Video struct {
-	ID               int64          `json:"id"`
-	Url              string         `json:"url"`
-	FileID           string         `json:"file_id"`
-	FileFormat       string         `json:"file_format"`
-	Title            string         `json:"title"`
-	DownloadComplete bool           `json:"download_complete"`
-	Duration         string         `json:"duration"`
-	DownloadDate     string         `json:"download_date"`
-	Md5Checksum      string         `json:"md5_checksum"`
-	VideoFormat      sql.NullString `json:"video_format"`
-}
+package models
+
+import "database/sql"
+
+// Whether the video is still online at its Url,
+// set by the availability checker
+const (
+	AvailabilityUnknown    = ""
+	AvailabilityAvailable  = "available"
+	AvailabilityRemoved    = "removed"
+	AvailabilityPrivate    = "private"
+	// Geo blocked or age restricted
+	AvailabilityRestricted = "restricted"
+)
+
+type Video struct {
+	ID               int64          `json:"id"`
+	Url              string         `json:"url"`
+	FileID           string         `json:"file_id"`
+	FileFormat       string         `json:"file_format"`
+	Title            string         `json:"title"`
+	DownloadComplete bool           `json:"download_complete"`
+	Duration         string         `json:"duration"`
+	DownloadDate     string         `json:"download_date"`
+	Md5Checksum      string         `json:"md5_checksum"`
+	VideoFormat      sql.NullString `json:"video_format"`
+	DownloadPaused   bool           `json:"download_paused"`
+	Uploader         string         `json:"uploader"`
+	Channel          string         `json:"channel"`
+	UploadDate       string         `json:"upload_date"`
+	Description      string         `json:"description"`
+	Tags             []string       `json:"tags"`
+	ViewCount        int64          `json:"view_count"`
+	WebpageUrl       string         `json:"webpage_url"`
+	// Subtitles to download with yt-dlp, auto generated 
+	// subtitles are used if there are none for the language
+	SubtitleLanguages []string      `json:"subtitle_languages"`
+	AutoSubtitles     bool          `json:"auto_subtitles"`
+	// Bytes per second, overrides the global rate limit if > 0
+	RateLimit         int64         `json:"rate_limit"`
+	// "mark", "remove" or empty, see ytdlp.SponsorBlockModes
+	SponsorBlockMode  string        `json:"sponsorblock_mode"`
+	Availability      string        `json:"availability"`
+	// Unix time, 0 if never checked
+	AvailabilityCheckedAt int64     `json:"availability_checked_at"`
+	// The yt-dlp extractor and the site's id of the video, eg. "Youtube"
+	// and "dQw4w9WgXcQ", the same for every url of the video
+	ExtractorKey      string        `json:"extractor_key"`
+	SourceID          string        `json:"source_id"`
+	// "now" or "start" for live stream recordings, see ytdlp.IsLiveMode
+	LiveMode          string        `json:"live_mode"`
+	// Folder of an imported file relative to the imported folder,
+	// eg. "2021/03", to group videos by their folder on disk
+	SourceFolder      string        `json:"source_folder"`
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Videos from imported yt-dlp download archives, skipped
+// by subscriptions and playlist imports
+type ArchiveRepository struct {
+	db **sql.DB
+}
+
+func (repo *ArchiveRepository) GetDB() *sql.DB {
+	return *repo.db
+}
+
+func (repo *ArchiveRepository) SetDB(sql *sql.DB) {
+	repo.db = &sql
+}
+
+// Adds the entries, ignoring the ones already in the archive.
+// Returns the number of entries added.
+func (repo *ArchiveRepository) Add(entries []models.ArchiveEntry) (int64, error) {
+	tx, err := repo.GetDB().Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	createdAt := time.Now().Unix()
+	var added int64
+
+	for _, entry := range entries {
+		result, err := tx.Exec(
+			"INSERT OR IGNORE INTO archive_entries (extractor, source_id, created_at) VALUES (?, ?, ?)",
+			strings.ToLower(entry.Extractor),
+			entry.SourceID,
+			createdAt,
+		)
+
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		rowsAffected, _ := result.RowsAffected()
+		added += rowsAffected
+	}
+
+	return added, tx.Commit()
+}
+
+// Whether the video of the extractor key (eg. "Youtube") and id
+// was imported, false if the id is empty
+func (repo *ArchiveRepository) Has(extractorKey string, sourceID string) (bool, error) {
+	if sourceID == "" {
+		return false, nil
+	}
+
+	var count int
+	err := repo.GetDB().QueryRow(
+		"SELECT COUNT(*) FROM archive_entries WHERE extractor = ? AND source_id = ?",
+		strings.ToLower(extractorKey),
+		sourceID,
+	).Scan(&count)
+
+	return count > 0, err
+}
+
+// Returns the imported entries and the downloaded videos with
+// a source id, sorted and without duplicates
+func (repo *ArchiveRepository) Export() ([]models.ArchiveEntry, error) {
+	rows, err := repo.GetDB().Query(`
+		SELECT extractor, source_id FROM archive_entries
+		UNION
+		SELECT lower(extractor_key), source_id FROM videos
+		WHERE download_complete = 1 AND extractor_key != '' AND source_id != ''
+		ORDER BY 1, 2
+	`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []models.ArchiveEntry{}
+
+	for rows.Next() {
+		entry := models.ArchiveEntry{}
+		err := rows.Scan(&entry.Extractor, &entry.SourceID)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
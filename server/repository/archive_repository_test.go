@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"testing"
+	"vidviewer/models"
+)
+
+func TestArchive(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := ArchiveRepository{db: &db}
+	videoRepo := newTestRepo(t, db)
+
+	added, err := repo.Add([]models.ArchiveEntry{
+		{Extractor: "youtube", SourceID: "abc"},
+		{Extractor: "Youtube", SourceID: "abc"},
+		{Extractor: "vimeo", SourceID: "123"},
+	})
+
+	if err != nil {
+		t.Fatalf("Error adding entries: %s\n", err)
+	}
+
+	if added != 2 {
+		t.Errorf("Expected 2 entries to be added, got %d", added)
+	}
+
+	if has, _ := repo.Has("Youtube", "abc"); !has {
+		t.Errorf("Expected the archive to have youtube abc")
+	}
+
+	if has, _ := repo.Has("Vimeo", "abc"); has {
+		t.Errorf("Expected the archive not to have vimeo abc")
+	}
+
+	video := newVideo()
+	video.ExtractorKey = "Youtube"
+	video.SourceID = "xyz"
+	videoRepo.Create(video)
+
+	incompleteVideo := newVideo()
+	incompleteVideo.ExtractorKey = "Youtube"
+	incompleteVideo.SourceID = "incomplete"
+	incompleteVideo.DownloadComplete = false
+	videoRepo.Create(incompleteVideo)
+
+	entries, err := repo.Export()
+
+	if err != nil {
+		t.Fatalf("Error exporting entries: %s\n", err)
+	}
+
+	expected := []models.ArchiveEntry{
+		{Extractor: "vimeo", SourceID: "123"},
+		{Extractor: "youtube", SourceID: "abc"},
+		{Extractor: "youtube", SourceID: "xyz"},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), entries)
+	}
+
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("Expected entry %+v, got %+v", expected[i], entries[i])
+		}
+	}
+}
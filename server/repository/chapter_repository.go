@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type ChapterRepository struct {
+	db **sql.DB
+}
+
+func (repo *ChapterRepository) GetDB() *sql.DB {
+	return *repo.db
+}
+
+func (repo *ChapterRepository) SetDB(sql *sql.DB) {
+	repo.db = &sql
+}
+
+// Returns the chapters of the video ordered by start time
+func (repo *ChapterRepository) GetByVideo(videoID string) ([]models.Chapter, error) {
+	rows, err := repo.GetDB().Query(
+		"SELECT id, video_id, title, start_time, end_time FROM chapters WHERE video_id = ? ORDER BY start_time, id",
+		videoID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	chapters := []models.Chapter{}
+
+	for rows.Next() {
+		chapter := models.Chapter{}
+		err := rows.Scan(&chapter.ID, &chapter.VideoID, &chapter.Title, &chapter.StartTime, &chapter.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, chapter)
+	}
+
+	return chapters, rows.Err()
+}
+
+// Replaces all chapters of the video
+func (repo *ChapterRepository) SetVideoChapters(videoID int64, chapters []models.Chapter) error {
+	tx, err := repo.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM chapters WHERE video_id = ?", videoID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, chapter := range chapters {
+		_, err = tx.Exec(
+			"INSERT INTO chapters (video_id, title, start_time, end_time) VALUES (?, ?, ?, ?)",
+			videoID,
+			chapter.Title,
+			chapter.StartTime,
+			chapter.EndTime,
+		)
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo *ChapterRepository) OnDeleteVideo(videoID string) error {
+	_, err := repo.GetDB().Exec("DELETE FROM chapters WHERE video_id = ?", videoID)
+	return err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"testing"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSetVideoChapters(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := ChapterRepository{db: &db}
+
+	err := repo.SetVideoChapters(1, []models.Chapter{
+		{Title: "Outro", StartTime: 60, EndTime: 90},
+		{Title: "Intro", StartTime: 0, EndTime: 60},
+	})
+
+	if err != nil {
+		t.Fatalf("Error saving chapters: %s", err)
+	}
+
+	repo.SetVideoChapters(2, []models.Chapter{{Title: "Other", StartTime: 0, EndTime: 10}})
+
+	chapters, err := repo.GetByVideo("1")
+	if err != nil {
+		t.Fatalf("Error getting chapters: %s", err)
+	}
+
+	if len(chapters) != 2 || chapters[0].Title != "Intro" || chapters[1].Title != "Outro" {
+		t.Errorf("Expected the chapters of video 1 ordered by start time, got %+v", chapters)
+	}
+
+	// Replaces the existing chapters
+	repo.SetVideoChapters(1, []models.Chapter{{Title: "Edited", StartTime: 0, EndTime: 90}})
+
+	chapters, _ = repo.GetByVideo("1")
+
+	if len(chapters) != 1 || chapters[0].Title != "Edited" {
+		t.Errorf("Expected only the edited chapter, got %+v", chapters)
+	}
+
+	repo.OnDeleteVideo("1")
+
+	chapters, _ = repo.GetByVideo("1")
+
+	if len(chapters) != 0 {
+		t.Errorf("Expected chapters of deleted video to be removed, got %+v", chapters)
+	}
+
+	chapters, _ = repo.GetByVideo("2")
+
+	if len(chapters) != 1 {
+		t.Errorf("Expected chapters of other videos to be kept, got %+v", chapters)
+	}
+}
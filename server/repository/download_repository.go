@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type DownloadRepository struct {
+	db **sql.DB
+}
+
+func (repo *DownloadRepository) GetDB() *sql.DB {
+	return *repo.db
+}
+
+func (repo *DownloadRepository) SetDB(sql *sql.DB) {
+	repo.db = &sql
+}
+
+// Filters for the download history, empty/zero values are ignored
+type DownloadFilter struct {
+	Status  string
+	VideoID int64
+	Search  string // matched against url and title
+	Since   int64  // unix time, downloads started at or after
+	Until   int64  // unix time, downloads started before
+}
+
+const downloadColumns = `id, video_id, url, title, format, status, error, error_reason, time_started, time_completed, bytes_downloaded, total_bytes, average_speed`
+
+func scanDownload(row rowScanner, download *models.Download) error {
+	return row.Scan(
+		&download.ID,
+		&download.VideoID,
+		&download.Url,
+		&download.Title,
+		&download.Format,
+		&download.Status,
+		&download.Error,
+		&download.ErrorReason,
+		&download.TimeStarted,
+		&download.TimeCompleted,
+		&download.BytesDownloaded,
+		&download.TotalBytes,
+		&download.AverageSpeed,
+	)
+}
+
+func (repo *DownloadRepository) Create(download models.Download) (int64, error) {
+	result, err := repo.GetDB().Exec(`
+		INSERT INTO downloads (video_id, url, title, format, status, error, error_reason, time_started, time_completed, bytes_downloaded, total_bytes, average_speed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		download.VideoID,
+		download.Url,
+		download.Title,
+		download.Format,
+		download.Status,
+		download.Error,
+		download.ErrorReason,
+		download.TimeStarted,
+		download.TimeCompleted,
+		download.BytesDownloaded,
+		download.TotalBytes,
+		download.AverageSpeed,
+	)
+
+	if err != nil {
+		return -1, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (repo *DownloadRepository) Update(download models.Download) error {
+	_, err := repo.GetDB().Exec(`
+		UPDATE downloads
+		SET video_id = ?,
+		url = ?,
+		title = ?,
+		format = ?,
+		status = ?,
+		error = ?,
+		error_reason = ?,
+		time_started = ?,
+		time_completed = ?,
+		bytes_downloaded = ?,
+		total_bytes = ?,
+		average_speed = ?
+		WHERE id = ?
+	`,
+		download.VideoID,
+		download.Url,
+		download.Title,
+		download.Format,
+		download.Status,
+		download.Error,
+		download.ErrorReason,
+		download.TimeStarted,
+		download.TimeCompleted,
+		download.BytesDownloaded,
+		download.TotalBytes,
+		download.AverageSpeed,
+		download.ID,
+	)
+
+	return err
+}
+
+// Returns nil if the download does not exist
+func (repo *DownloadRepository) Get(id string) (*models.Download, error) {
+	download := &models.Download{}
+	err := scanDownload(repo.GetDB().QueryRow("SELECT "+downloadColumns+" FROM downloads WHERE id = ?", id), download)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return download, nil
+}
+
+// Returns the most recent download of the video that has not
+// completed, failed or been cancelled. Returns nil if there is none.
+func (repo *DownloadRepository) GetUnfinished(videoID int64) (*models.Download, error) {
+	download := &models.Download{}
+	query := "SELECT " + downloadColumns + ` FROM downloads
+		WHERE video_id = ? AND status NOT IN (?, ?, ?)
+		ORDER BY id DESC
+		LIMIT 1`
+
+	err := scanDownload(repo.GetDB().QueryRow(
+		query,
+		videoID,
+		models.DownloadStatusComplete,
+		models.DownloadStatusCancelled,
+		models.DownloadStatusFailed,
+	), download)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return download, nil
+}
+
+// Returns the download history, newest first
+func (repo *DownloadRepository) Index(filter DownloadFilter, limit uint, page uint) ([]models.Download, error) {
+	where := []string{"1 = 1"}
+	args := []interface{}{}
+
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+
+	if filter.VideoID != 0 {
+		where = append(where, "video_id = ?")
+		args = append(args, filter.VideoID)
+	}
+
+	if filter.Search != "" {
+		where = append(where, "(url LIKE ? OR title LIKE ?)")
+		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+
+	if filter.Since != 0 {
+		where = append(where, "time_started >= ?")
+		args = append(args, filter.Since)
+	}
+
+	if filter.Until != 0 {
+		where = append(where, "time_started < ?")
+		args = append(args, filter.Until)
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	query := "SELECT " + downloadColumns + ` FROM downloads
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY id DESC
+		LIMIT ?
+		OFFSET ?`
+
+	args = append(args, limit, (page-1)*limit)
+
+	rows, err := repo.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	downloads := []models.Download{}
+
+	for rows.Next() {
+		download := models.Download{}
+		err := scanDownload(rows, &download)
+		if err != nil {
+			return nil, err
+		}
+		downloads = append(downloads, download)
+	}
+
+	return downloads, rows.Err()
+}
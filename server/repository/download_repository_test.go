@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"strconv"
+	"testing"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newDownload(videoID int64, status string, timeStarted int64) models.Download {
+	return models.Download{
+		VideoID:     videoID,
+		Url:         "https://example.com/" + strconv.FormatInt(videoID, 10),
+		Title:       "video " + strconv.FormatInt(videoID, 10),
+		Status:      status,
+		TimeStarted: timeStarted,
+	}
+}
+
+func TestCreateAndGetDownload(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := DownloadRepository{db: &db}
+
+	id, err := repo.Create(newDownload(1, models.DownloadStatusQueued, 100))
+	if err != nil {
+		t.Fatalf("Error creating download: %s", err)
+	}
+
+	download, err := repo.Get(strconv.FormatInt(id, 10))
+	if err != nil {
+		t.Fatalf("Error getting download: %s", err)
+	}
+
+	if download == nil || download.VideoID != 1 || download.Status != models.DownloadStatusQueued {
+		t.Errorf("Expected to get the created download, got %+v", download)
+	}
+
+	download, err = repo.Get("1000")
+	if err != nil || download != nil {
+		t.Errorf("Expected nil download and error for missing id, got %+v %v", download, err)
+	}
+}
+
+func TestUpdateDownload(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := DownloadRepository{db: &db}
+
+	download := newDownload(1, models.DownloadStatusDownloading, 100)
+	id, err := repo.Create(download)
+	if err != nil {
+		t.Fatalf("Error creating download: %s", err)
+	}
+
+	download.ID = id
+	download.Status = models.DownloadStatusComplete
+	download.TimeCompleted = 110
+	download.BytesDownloaded = 1000
+	download.AverageSpeed = 100
+
+	err = repo.Update(download)
+	if err != nil {
+		t.Fatalf("Error updating download: %s", err)
+	}
+
+	updated, _ := repo.Get(strconv.FormatInt(id, 10))
+
+	if updated.Status != models.DownloadStatusComplete || updated.BytesDownloaded != 1000 || updated.AverageSpeed != 100 {
+		t.Errorf("Expected download to be updated, got %+v", updated)
+	}
+}
+
+func TestGetUnfinishedDownload(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := DownloadRepository{db: &db}
+
+	repo.Create(newDownload(1, models.DownloadStatusFailed, 100))
+
+	download, err := repo.GetUnfinished(1)
+	if err != nil || download != nil {
+		t.Errorf("Expected no unfinished download, got %+v %v", download, err)
+	}
+
+	id, _ := repo.Create(newDownload(1, models.DownloadStatusPaused, 200))
+
+	download, err = repo.GetUnfinished(1)
+	if err != nil {
+		t.Fatalf("Error getting unfinished download: %s", err)
+	}
+
+	if download == nil || download.ID != id {
+		t.Errorf("Expected the paused download to be returned, got %+v", download)
+	}
+}
+
+func TestIndexDownloads(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := DownloadRepository{db: &db}
+
+	repo.Create(newDownload(1, models.DownloadStatusComplete, 100))
+	repo.Create(newDownload(2, models.DownloadStatusFailed, 200))
+	repo.Create(newDownload(3, models.DownloadStatusComplete, 300))
+
+	downloads, err := repo.Index(DownloadFilter{}, 10, 1)
+	if err != nil {
+		t.Fatalf("Error getting downloads: %s", err)
+	}
+
+	if len(downloads) != 3 || downloads[0].VideoID != 3 {
+		t.Errorf("Expected all downloads, newest first, got %+v", downloads)
+	}
+
+	downloads, _ = repo.Index(DownloadFilter{Status: models.DownloadStatusComplete}, 10, 1)
+
+	if len(downloads) != 2 {
+		t.Errorf("Expected 2 complete downloads, got %d", len(downloads))
+	}
+
+	downloads, _ = repo.Index(DownloadFilter{Since: 150, Until: 300}, 10, 1)
+
+	if len(downloads) != 1 || downloads[0].VideoID != 2 {
+		t.Errorf("Expected only the download started between 150 and 300, got %+v", downloads)
+	}
+
+	downloads, _ = repo.Index(DownloadFilter{Search: "example.com/1"}, 10, 1)
+
+	if len(downloads) != 1 || downloads[0].VideoID != 1 {
+		t.Errorf("Expected search to match the download url, got %+v", downloads)
+	}
+
+	downloads, _ = repo.Index(DownloadFilter{}, 2, 2)
+
+	if len(downloads) != 1 || downloads[0].VideoID != 1 {
+		t.Errorf("Expected page 2 with limit 2 to return the oldest download, got %+v", downloads)
+	}
+}
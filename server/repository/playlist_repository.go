@@ -101,10 +101,14 @@ func (repo *PlaylistRepository) Create(name string, date string) (int64, error)
 		return 0, err
 	}
 
+	defer stmt.Close()
+
 	// Execute the SQL statement with the values for the row
-	execution, _ := stmt.Exec(name, date)
+	execution, err := stmt.Exec(name, date)
 
-	execution.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
 
 	id, err := execution.LastInsertId()
 	if err != nil {
@@ -1,20 +1,53 @@
 package repository
 
+import "database/sql"
+
 type Repositories struct {
     VideoRepo    VideoRepository
     PlaylistRepo PlaylistRepository
     PlaylistVideoRepo PlaylistVideoRepository
+    DownloadRepo DownloadRepository
+    SubscriptionRepo SubscriptionRepository
+    SubtitleRepo SubtitleRepository
+    ChapterRepo ChapterRepository
+    SponsorBlockSegmentRepo SponsorBlockSegmentRepository
+    ArchiveRepo ArchiveRepository
 }
 
 func NewRepositories() *Repositories {
 	videoRepo    := VideoRepository{}
 	playlistRepo := PlaylistRepository{}
 	playlistVideoRepo := PlaylistVideoRepository{}
+	downloadRepo := DownloadRepository{}
+	subscriptionRepo := SubscriptionRepository{}
+	subtitleRepo := SubtitleRepository{}
+	chapterRepo := ChapterRepository{}
+	sponsorBlockSegmentRepo := SponsorBlockSegmentRepository{}
+	archiveRepo := ArchiveRepository{}
 
     return &Repositories{
         VideoRepo:   videoRepo,
         PlaylistRepo: playlistRepo,
         PlaylistVideoRepo: playlistVideoRepo,
+        DownloadRepo: downloadRepo,
+        SubscriptionRepo: subscriptionRepo,
+        SubtitleRepo: subtitleRepo,
+        ChapterRepo: chapterRepo,
+        SponsorBlockSegmentRepo: sponsorBlockSegmentRepo,
+        ArchiveRepo: archiveRepo,
     }
 }
 
+
+// Points every repository to the database connection
+func (r *Repositories) SetDB(sql *sql.DB) {
+    r.PlaylistRepo.SetDB(sql)
+    r.VideoRepo.SetDB(sql)
+    r.PlaylistVideoRepo.SetDB(sql)
+    r.DownloadRepo.SetDB(sql)
+    r.SubscriptionRepo.SetDB(sql)
+    r.SubtitleRepo.SetDB(sql)
+    r.ChapterRepo.SetDB(sql)
+    r.SponsorBlockSegmentRepo.SetDB(sql)
+    r.ArchiveRepo.SetDB(sql)
+}
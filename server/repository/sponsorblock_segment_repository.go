@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type SponsorBlockSegmentRepository struct {
+	db **sql.DB
+}
+
+func (repo *SponsorBlockSegmentRepository) GetDB() *sql.DB {
+	return *repo.db
+}
+
+func (repo *SponsorBlockSegmentRepository) SetDB(sql *sql.DB) {
+	repo.db = &sql
+}
+
+// Returns the segments of the video ordered by start time
+func (repo *SponsorBlockSegmentRepository) GetByVideo(videoID string) ([]models.SponsorBlockSegment, error) {
+	rows, err := repo.GetDB().Query(
+		"SELECT id, video_id, category, action, start_time, end_time FROM sponsorblock_segments WHERE video_id = ? ORDER BY start_time, id",
+		videoID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	segments := []models.SponsorBlockSegment{}
+
+	for rows.Next() {
+		segment := models.SponsorBlockSegment{}
+		err := rows.Scan(&segment.ID, &segment.VideoID, &segment.Category, &segment.Action, &segment.StartTime, &segment.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, rows.Err()
+}
+
+// Replaces all segments of the video
+func (repo *SponsorBlockSegmentRepository) SetVideoSegments(videoID int64, segments []models.SponsorBlockSegment) error {
+	tx, err := repo.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM sponsorblock_segments WHERE video_id = ?", videoID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, segment := range segments {
+		_, err = tx.Exec(
+			"INSERT INTO sponsorblock_segments (video_id, category, action, start_time, end_time) VALUES (?, ?, ?, ?, ?)",
+			videoID,
+			segment.Category,
+			segment.Action,
+			segment.StartTime,
+			segment.EndTime,
+		)
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo *SponsorBlockSegmentRepository) OnDeleteVideo(videoID string) error {
+	_, err := repo.GetDB().Exec("DELETE FROM sponsorblock_segments WHERE video_id = ?", videoID)
+	return err
+}
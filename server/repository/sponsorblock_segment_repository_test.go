@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"testing"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSetVideoSegments(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := SponsorBlockSegmentRepository{db: &db}
+
+	err := repo.SetVideoSegments(1, []models.SponsorBlockSegment{
+		{Category: models.SponsorBlockOutro, Action: models.SponsorBlockActionSkip, StartTime: 80, EndTime: 90},
+		{Category: models.SponsorBlockSponsor, Action: models.SponsorBlockActionSkip, StartTime: 10, EndTime: 25.5},
+	})
+
+	if err != nil {
+		t.Fatalf("Error saving segments: %s", err)
+	}
+
+	repo.SetVideoSegments(2, []models.SponsorBlockSegment{{Category: models.SponsorBlockIntro, Action: models.SponsorBlockActionSkip, StartTime: 0, EndTime: 5}})
+
+	segments, err := repo.GetByVideo("1")
+	if err != nil {
+		t.Fatalf("Error getting segments: %s", err)
+	}
+
+	if len(segments) != 2 || segments[0].Category != models.SponsorBlockSponsor || segments[0].EndTime != 25.5 || segments[1].Category != models.SponsorBlockOutro {
+		t.Errorf("Expected the segments of video 1 ordered by start time, got %+v", segments)
+	}
+
+	repo.OnDeleteVideo("1")
+
+	segments, _ = repo.GetByVideo("1")
+
+	if len(segments) != 0 {
+		t.Errorf("Expected segments of deleted video to be removed, got %+v", segments)
+	}
+
+	segments, _ = repo.GetByVideo("2")
+
+	if len(segments) != 1 {
+		t.Errorf("Expected segments of other videos to be kept, got %+v", segments)
+	}
+}
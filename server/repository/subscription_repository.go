@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"database/sql"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type SubscriptionRepository struct {
+	db **sql.DB
+}
+
+func (repo *SubscriptionRepository) GetDB() *sql.DB {
+	return *repo.db
+}
+
+func (repo *SubscriptionRepository) SetDB(sql *sql.DB) {
+	repo.db = &sql
+}
+
+const subscriptionColumns = `id, url, name, playlist_id, format, interval_minutes, last_checked, created_at, needs_seed`
+
+func scanSubscription(row rowScanner, subscription *models.Subscription) error {
+	return row.Scan(
+		&subscription.ID,
+		&subscription.Url,
+		&subscription.Name,
+		&subscription.PlaylistID,
+		&subscription.Format,
+		&subscription.IntervalMinutes,
+		&subscription.LastChecked,
+		&subscription.CreatedAt,
+		&subscription.NeedsSeed,
+	)
+}
+
+func (repo *SubscriptionRepository) Create(subscription models.Subscription) (int64, error) {
+	result, err := repo.GetDB().Exec(`
+		INSERT INTO subscriptions (url, name, playlist_id, format, interval_minutes, last_checked, created_at, needs_seed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		subscription.Url,
+		subscription.Name,
+		subscription.PlaylistID,
+		subscription.Format,
+		subscription.IntervalMinutes,
+		subscription.LastChecked,
+		subscription.CreatedAt,
+		subscription.NeedsSeed,
+	)
+
+	if err != nil {
+		return -1, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (repo *SubscriptionRepository) Update(subscription models.Subscription) error {
+	_, err := repo.GetDB().Exec(`
+		UPDATE subscriptions
+		SET url = ?,
+		name = ?,
+		playlist_id = ?,
+		format = ?,
+		interval_minutes = ?,
+		last_checked = ?,
+		needs_seed = ?
+		WHERE id = ?
+	`,
+		subscription.Url,
+		subscription.Name,
+		subscription.PlaylistID,
+		subscription.Format,
+		subscription.IntervalMinutes,
+		subscription.LastChecked,
+		subscription.NeedsSeed,
+		subscription.ID,
+	)
+
+	return err
+}
+
+// Returns nil if the subscription does not exist
+func (repo *SubscriptionRepository) Get(id string) (*models.Subscription, error) {
+	subscription := &models.Subscription{}
+	err := scanSubscription(repo.GetDB().QueryRow("SELECT "+subscriptionColumns+" FROM subscriptions WHERE id = ?", id), subscription)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (repo *SubscriptionRepository) Delete(id string) error {
+	_, err := repo.GetDB().Exec("DELETE FROM subscription_entries WHERE subscription_id = ?", id)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.GetDB().Exec("DELETE FROM subscriptions WHERE id = ?", id)
+	return err
+}
+
+// Records the entries, ignoring the ones already recorded
+func (repo *SubscriptionRepository) AddEntries(entries []models.SubscriptionEntry) error {
+	tx, err := repo.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		_, err := tx.Exec(
+			"INSERT OR IGNORE INTO subscription_entries (subscription_id, url, extractor_key, source_id) VALUES (?, ?, ?, ?)",
+			entry.SubscriptionID,
+			entry.Url,
+			entry.ExtractorKey,
+			entry.SourceID,
+		)
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Whether the video was recorded for the subscription, by its
+// url or its extractor key and id if it has one
+func (repo *SubscriptionRepository) HasEntry(subscriptionID int64, url string, extractorKey string, sourceID string) (bool, error) {
+	var count int
+	err := repo.GetDB().QueryRow(`
+		SELECT COUNT(*) FROM subscription_entries
+		WHERE subscription_id = ?
+		AND (url = ? OR (source_id != '' AND extractor_key = ? AND source_id = ?))
+	`,
+		subscriptionID,
+		url,
+		extractorKey,
+		sourceID,
+	).Scan(&count)
+
+	return count > 0, err
+}
+
+func (repo *SubscriptionRepository) Index() ([]models.Subscription, error) {
+	return repo.query("SELECT " + subscriptionColumns + " FROM subscriptions ORDER BY id")
+}
+
+// Returns the subscriptions that have not been checked
+// for longer than their interval
+func (repo *SubscriptionRepository) GetDue(now int64) ([]models.Subscription, error) {
+	return repo.query(
+		"SELECT "+subscriptionColumns+" FROM subscriptions WHERE last_checked + interval_minutes * 60 <= ? ORDER BY last_checked",
+		now,
+	)
+}
+
+func (repo *SubscriptionRepository) SetLastChecked(id int64, lastChecked int64) error {
+	_, err := repo.GetDB().Exec("UPDATE subscriptions SET last_checked = ? WHERE id = ?", lastChecked, id)
+	return err
+}
+
+// Marks the subscription as seeded, unless its url
+// changed since the videos of url were recorded
+func (repo *SubscriptionRepository) SetSeeded(id int64, url string) error {
+	_, err := repo.GetDB().Exec("UPDATE subscriptions SET needs_seed = 0 WHERE id = ? AND url = ?", id, url)
+	return err
+}
+
+func (repo *SubscriptionRepository) query(query string, args ...interface{}) ([]models.Subscription, error) {
+	rows, err := repo.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	subscriptions := []models.Subscription{}
+
+	for rows.Next() {
+		subscription := models.Subscription{}
+		err := scanSubscription(rows, &subscription)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
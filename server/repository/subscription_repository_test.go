@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"strconv"
+	"testing"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCreateAndGetSubscription(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := SubscriptionRepository{db: &db}
+
+	id, err := repo.Create(models.Subscription{Url: "https://example.com/channel", IntervalMinutes: 60})
+	if err != nil {
+		t.Fatalf("Error creating subscription: %s", err)
+	}
+
+	subscription, err := repo.Get(strconv.FormatInt(id, 10))
+	if err != nil {
+		t.Fatalf("Error getting subscription: %s", err)
+	}
+
+	if subscription == nil || subscription.Url != "https://example.com/channel" {
+		t.Errorf("Expected to get the created subscription, got %+v", subscription)
+	}
+
+	_, err = repo.Create(models.Subscription{Url: "https://example.com/channel", IntervalMinutes: 60})
+	if err == nil {
+		t.Errorf("Expected an error creating a subscription with a duplicate url")
+	}
+
+	err = repo.Delete(strconv.FormatInt(id, 10))
+	if err != nil {
+		t.Fatalf("Error deleting subscription: %s", err)
+	}
+
+	subscription, err = repo.Get(strconv.FormatInt(id, 10))
+	if err != nil || subscription != nil {
+		t.Errorf("Expected deleted subscription to be nil, got %+v %v", subscription, err)
+	}
+}
+
+func TestGetDueSubscriptions(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := SubscriptionRepository{db: &db}
+
+	dueID, _ := repo.Create(models.Subscription{Url: "https://example.com/1", IntervalMinutes: 60, LastChecked: 0})
+	notDueID, _ := repo.Create(models.Subscription{Url: "https://example.com/2", IntervalMinutes: 60, LastChecked: 10000})
+
+	subscriptions, err := repo.GetDue(10000)
+	if err != nil {
+		t.Fatalf("Error getting due subscriptions: %s", err)
+	}
+
+	if len(subscriptions) != 1 || subscriptions[0].ID != dueID {
+		t.Errorf("Expected only the unchecked subscription to be due, got %+v", subscriptions)
+	}
+
+	repo.SetLastChecked(dueID, 10000)
+
+	subscriptions, _ = repo.GetDue(10000 + 60*60)
+
+	if len(subscriptions) != 2 {
+		t.Errorf("Expected both subscriptions to be due after an hour, got %+v", subscriptions)
+	}
+
+	repo.SetLastChecked(notDueID, 20000)
+
+	subscriptions, _ = repo.GetDue(10000 + 60*60)
+
+	if len(subscriptions) != 1 || subscriptions[0].ID != dueID {
+		t.Errorf("Expected the checked subscription to no longer be due, got %+v", subscriptions)
+	}
+}
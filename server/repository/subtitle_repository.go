@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"database/sql"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type SubtitleRepository struct {
+	db **sql.DB
+}
+
+func (repo *SubtitleRepository) GetDB() *sql.DB {
+	return *repo.db
+}
+
+func (repo *SubtitleRepository) SetDB(sql *sql.DB) {
+	repo.db = &sql
+}
+
+// Adds the subtitle, replacing the video's 
+// subtitle for the language if there is one
+func (repo *SubtitleRepository) Create(subtitle models.Subtitle) (int64, error) {
+	result, err := repo.GetDB().Exec(`
+		INSERT OR REPLACE INTO subtitles (video_id, language, auto_generated)
+		VALUES (?, ?, ?)
+	`,
+		subtitle.VideoID,
+		subtitle.Language,
+		subtitle.AutoGenerated,
+	)
+
+	if err != nil {
+		return -1, err
+	}
+
+	return result.LastInsertId()
+}
+
+// Returns nil if the video has no subtitle for the language
+func (repo *SubtitleRepository) Get(videoID string, language string) (*models.Subtitle, error) {
+	subtitle := &models.Subtitle{}
+	err := repo.GetDB().QueryRow(
+		"SELECT id, video_id, language, auto_generated FROM subtitles WHERE video_id = ? AND language = ?",
+		videoID,
+		language,
+	).Scan(&subtitle.ID, &subtitle.VideoID, &subtitle.Language, &subtitle.AutoGenerated)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return subtitle, nil
+}
+
+func (repo *SubtitleRepository) GetByVideo(videoID string) ([]models.Subtitle, error) {
+	rows, err := repo.GetDB().Query(
+		"SELECT id, video_id, language, auto_generated FROM subtitles WHERE video_id = ? ORDER BY language",
+		videoID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	subtitles := []models.Subtitle{}
+
+	for rows.Next() {
+		subtitle := models.Subtitle{}
+		err := rows.Scan(&subtitle.ID, &subtitle.VideoID, &subtitle.Language, &subtitle.AutoGenerated)
+		if err != nil {
+			return nil, err
+		}
+		subtitles = append(subtitles, subtitle)
+	}
+
+	return subtitles, rows.Err()
+}
+
+func (repo *SubtitleRepository) OnDeleteVideo(videoID string) error {
+	_, err := repo.GetDB().Exec("DELETE FROM subtitles WHERE video_id = ?", videoID)
+	return err
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"testing"
+	"vidviewer/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCreateAndGetSubtitles(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := SubtitleRepository{db: &db}
+
+	_, err := repo.Create(models.Subtitle{VideoID: 1, Language: "en", AutoGenerated: true})
+	if err != nil {
+		t.Fatalf("Error creating subtitle: %s", err)
+	}
+
+	repo.Create(models.Subtitle{VideoID: 1, Language: "de"})
+	repo.Create(models.Subtitle{VideoID: 2, Language: "en"})
+
+	// Replaces the auto generated subtitle
+	repo.Create(models.Subtitle{VideoID: 1, Language: "en"})
+
+	subtitles, err := repo.GetByVideo("1")
+	if err != nil {
+		t.Fatalf("Error getting subtitles: %s", err)
+	}
+
+	if len(subtitles) != 2 || subtitles[0].Language != "de" || subtitles[1].Language != "en" {
+		t.Errorf("Expected the de and en subtitles of video 1, got %+v", subtitles)
+	}
+
+	subtitle, err := repo.Get("1", "en")
+	if err != nil || subtitle == nil || subtitle.AutoGenerated {
+		t.Errorf("Expected the manual en subtitle, got %+v %v", subtitle, err)
+	}
+
+	subtitle, err = repo.Get("1", "fr")
+	if err != nil || subtitle != nil {
+		t.Errorf("Expected no subtitle for a missing language, got %+v %v", subtitle, err)
+	}
+
+	err = repo.OnDeleteVideo("1")
+	if err != nil {
+		t.Fatalf("Error deleting subtitles: %s", err)
+	}
+
+	subtitles, _ = repo.GetByVideo("1")
+
+	if len(subtitles) != 0 {
+		t.Errorf("Expected subtitles of deleted video to be removed, got %+v", subtitles)
+	}
+}
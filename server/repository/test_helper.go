@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -39,6 +40,18 @@ func InitializeDB(t *testing.T) *sql.DB {
 	return db
 }
 
+// Creates a database in a temporary folder, for the tests of other
+// packages that can run at the same time as the repository tests
+func InitializeTempDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open SQLite database: %s\n", err)
+	}
+	applyMigrations(t, db)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
 func applyMigrations(t *testing.T, db *sql.DB) {
 	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
 	if err != nil {
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -24,6 +25,67 @@ func (repo *VideoRepository) SetDB(sql *sql.DB) {
 
 const ALL_PLAYLIST_ID string = "0"
 
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Scans a row selected with SELECT * FROM videos into video.
+// Columns are in the order they are added by the migrations.
+func scanVideo(row rowScanner, video *models.Video) error {
+	var tags, subtitleLanguages string
+
+	err := row.Scan(
+		&video.ID,
+		&video.Url,
+		&video.FileID,
+		&video.FileFormat,
+		&video.Title,
+		&video.Duration,
+		&video.DownloadComplete,
+		&video.DownloadDate,
+		&video.Md5Checksum,
+		&video.VideoFormat,
+		&video.DownloadPaused,
+		&video.Uploader,
+		&video.Channel,
+		&video.UploadDate,
+		&video.Description,
+		&tags,
+		&video.ViewCount,
+		&video.WebpageUrl,
+		&subtitleLanguages,
+		&video.AutoSubtitles,
+		&video.RateLimit,
+		&video.SponsorBlockMode,
+		&video.Availability,
+		&video.AvailabilityCheckedAt,
+		&video.ExtractorKey,
+		&video.SourceID,
+		&video.LiveMode,
+		&video.SourceFolder,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal([]byte(tags), &video.Tags)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(subtitleLanguages), &video.SubtitleLanguages)
+}
+
+// Lists (tags, subtitle languages) are stored as JSON arrays
+func encodeStrings(values []string) string {
+	if values == nil {
+		values = []string{}
+	}
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
+
 func (repo *VideoRepository) GetIncompleteDownloads() ([]*models.Video, error)  {
 	videos, error := repo.GetAllBy("download_complete", "0")
 	if error != nil {
@@ -46,7 +108,7 @@ func (repo *VideoRepository) GetAllBy(by string, value string) ([]*models.Video,
 
     for rows.Next() {
         video := &models.Video{}
-        err = rows.Scan(&video.ID, &video.Url, &video.FileID, &video.FileFormat, &video.Title, &video.Duration, &video.DownloadComplete, &video.DownloadDate, &video.Md5Checksum, &video.VideoFormat)
+        err = scanVideo(rows, video)
         if err != nil {
             return nil, err
         }
@@ -67,7 +129,7 @@ func (repo *VideoRepository) GetBy(value string, by string) (*models.Video, erro
 	video := models.Video{}
     query := fmt.Sprintf("SELECT * FROM videos WHERE %s = ?", by)
 
-	err := repo.GetDB().QueryRow(query, value).Scan(&video.ID, &video.Url, &video.FileID, &video.FileFormat, &video.Title, &video.Duration, &video.DownloadComplete, &video.DownloadDate, &video.Md5Checksum, &video.VideoFormat)
+	err := scanVideo(repo.GetDB().QueryRow(query, value), &video)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -80,6 +142,37 @@ func (repo *VideoRepository) GetBy(value string, by string) (*models.Video, erro
 	return &video, nil
 }
 
+// Returns nil if no video has the extractor key and source id
+func (repo *VideoRepository) GetBySource(extractorKey string, sourceID string) (*models.Video, error) {
+	if sourceID == "" {
+		return nil, nil
+	}
+
+	video := models.Video{}
+
+	err := scanVideo(repo.GetDB().QueryRow("SELECT * FROM videos WHERE extractor_key = ? AND source_id = ?", extractorKey, sourceID), &video)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &video, nil
+}
+
+// Returns the video with the url, or else the same video
+// under another url (eg. youtu.be/X and youtube.com/watch?v=X)
+func (repo *VideoRepository) GetExisting(url string, extractorKey string, sourceID string) (*models.Video, error) {
+	video, err := repo.GetBy(url, "url")
+
+	if video != nil || err != nil {
+		return video, err
+	}
+
+	return repo.GetBySource(extractorKey, sourceID)
+}
+
 // Get the video
 func (repo *VideoRepository) Get(id string) (*models.Video, error) {
 	// Check if the video exists
@@ -96,7 +189,7 @@ func (repo *VideoRepository) Get(id string) (*models.Video, error) {
 
 	// If the video exists, retrieve it
 	video := &models.Video{}
-	err = repo.GetDB().QueryRow("SELECT * FROM videos WHERE id = ?", id).Scan(&video.ID, &video.Url, &video.FileID, &video.FileFormat, &video.Title, &video.Duration, &video.DownloadComplete, &video.DownloadDate, &video.Md5Checksum, &video.VideoFormat)
+	err = scanVideo(repo.GetDB().QueryRow("SELECT * FROM videos WHERE id = ?", id), video)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +209,23 @@ func (repo *VideoRepository) Update(video models.Video) error {
 	  duration = ?,
 	  download_date = ?,
 	  md5_checksum = ?,
-	  video_format =  ?
+	  video_format =  ?,
+	  download_paused = ?,
+	  uploader = ?,
+	  channel = ?,
+	  upload_date = ?,
+	  description = ?,
+	  tags = ?,
+	  view_count = ?,
+	  webpage_url = ?,
+	  subtitle_languages = ?,
+	  auto_subtitles = ?,
+	  rate_limit = ?,
+	  sponsorblock_mode = ?,
+	  extractor_key = ?,
+	  source_id = ?,
+	  live_mode = ?,
+	  source_folder = ?
 	  WHERE id = ?
 	`)
 
@@ -136,6 +245,22 @@ func (repo *VideoRepository) Update(video models.Video) error {
 		video.DownloadDate,
 		video.Md5Checksum,
 		video.VideoFormat,
+		video.DownloadPaused,
+		video.Uploader,
+		video.Channel,
+		video.UploadDate,
+		video.Description,
+		encodeStrings(video.Tags),
+		video.ViewCount,
+		video.WebpageUrl,
+		encodeStrings(video.SubtitleLanguages),
+		video.AutoSubtitles,
+		video.RateLimit,
+		video.SponsorBlockMode,
+		video.ExtractorKey,
+		video.SourceID,
+		video.LiveMode,
+		video.SourceFolder,
 		video.ID,
 	)
 
@@ -149,8 +274,8 @@ func (repo *VideoRepository) Update(video models.Video) error {
 // Insert video it into videos table
 func (repo *VideoRepository) Create(video models.Video) (int64, error) {
 	createVideoStatement, err := repo.GetDB().Prepare(`
-		INSERT INTO videos (download_date, url, title,   file_id, duration, download_complete, file_format, md5_checksum, video_format) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO videos (download_date, url, title,   file_id, duration, download_complete, file_format, md5_checksum, video_format, download_paused, uploader, channel, upload_date, description, tags, view_count, webpage_url, subtitle_languages, auto_subtitles, rate_limit, sponsorblock_mode, extractor_key, source_id, live_mode, source_folder) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return -1, err
@@ -158,7 +283,7 @@ func (repo *VideoRepository) Create(video models.Video) (int64, error) {
 
 	defer createVideoStatement.Close()
 
-	result, err := createVideoStatement.Exec(video.DownloadDate, video.Url, video.Title, video.FileID, video.Duration, video.DownloadComplete, video.FileFormat, video.Md5Checksum, video.VideoFormat)
+	result, err := createVideoStatement.Exec(video.DownloadDate, video.Url, video.Title, video.FileID, video.Duration, video.DownloadComplete, video.FileFormat, video.Md5Checksum, video.VideoFormat, video.DownloadPaused, video.Uploader, video.Channel, video.UploadDate, video.Description, encodeStrings(video.Tags), video.ViewCount, video.WebpageUrl, encodeStrings(video.SubtitleLanguages), video.AutoSubtitles, video.RateLimit, video.SponsorBlockMode, video.ExtractorKey, video.SourceID, video.LiveMode, video.SourceFolder)
 
 	// Check if error processing sql statement
 	if err != nil {
@@ -174,6 +299,47 @@ func (repo *VideoRepository) Create(video models.Video) (int64, error) {
 	return videoID, nil
 }
 
+// Returns complete videos with a url that were last checked
+// before checkedBefore, least recently checked first
+func (repo *VideoRepository) GetAvailabilityDue(checkedBefore int64, limit int) ([]models.Video, error) {
+	rows, err := repo.GetDB().Query(`
+		SELECT * FROM videos
+		WHERE download_complete = 1 AND url != '' AND availability_checked_at < ?
+		ORDER BY availability_checked_at, id
+		LIMIT ?
+	`, checkedBefore, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	videos := []models.Video{}
+
+	for rows.Next() {
+		video := models.Video{}
+		err := scanVideo(rows, &video)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+
+	return videos, rows.Err()
+}
+
+func (repo *VideoRepository) SetAvailability(id int64, availability string, checkedAt int64) error {
+	_, err := repo.GetDB().Exec("UPDATE videos SET availability = ?, availability_checked_at = ? WHERE id = ?", availability, checkedAt, id)
+	return err
+}
+
+// Persist whether the user paused the video's download
+func (repo *VideoRepository) SetDownloadPaused(id int64, paused bool) error {
+	_, err := repo.GetDB().Exec("UPDATE videos SET download_paused = ? WHERE id = ?", paused, id)
+	return err
+}
+
 // Delete video from videos table
 func (repo *VideoRepository) Delete(id string) error {
 	stmt, err := repo.GetDB().Prepare("DELETE FROM videos WHERE id = ?")
@@ -194,7 +360,8 @@ func (repo *VideoRepository) Delete(id string) error {
 }
 
 // Returns all videos belonging to playlist
-func (repo *VideoRepository) GetFromPlaylist(playlistID string, limit uint, page uint, like string, sortBy uint) ([]models.Video, error) {
+// sourceRemoved only returns videos found removed or private at their url
+func (repo *VideoRepository) GetFromPlaylist(playlistID string, limit uint, page uint, like string, sortBy uint, sourceRemoved bool) ([]models.Video, error) {
     var query string
 	var rows *sql.Rows
 	var err error
@@ -207,6 +374,10 @@ func (repo *VideoRepository) GetFromPlaylist(playlistID string, limit uint, page
         likeQuery = fmt.Sprintf(" AND title LIKE '%%%s%%'", like)
 	}
 
+	if sourceRemoved {
+		likeQuery += fmt.Sprintf(" AND availability IN ('%s', '%s')", models.AvailabilityRemoved, models.AvailabilityPrivate)
+	}
+
 	var sort = "ASC"
 
 	if sortBy == 0 {
@@ -247,7 +418,7 @@ func (repo *VideoRepository) GetFromPlaylist(playlistID string, limit uint, page
 	for rows.Next() {
 	    videoItem := models.Video{}
 		var video models.Video
-		 err := rows.Scan(&video.ID, &video.Url, &video.FileID, &video.FileFormat,  &video.Title, &video.Duration, &video.DownloadComplete, &video.DownloadDate, &video.Md5Checksum, &video.VideoFormat)
+		 err := scanVideo(rows, &video)
 		if err != nil {
 			log.Fatal(err)
 			return nil, err
@@ -259,6 +430,8 @@ func (repo *VideoRepository) GetFromPlaylist(playlistID string, limit uint, page
 		videoItem.Duration = video.Duration
 		videoItem.FileID = video.FileID
 		videoItem.Url = video.Url
+		videoItem.Availability = video.Availability
+		videoItem.AvailabilityCheckedAt = video.AvailabilityCheckedAt
 		videos = append(videos, videoItem)
 	}
 
@@ -262,7 +262,7 @@ func TestGetFromPlaylist(t *testing.T) {
 		t.Fatalf("Error creating videos %s", err)
 	}
 
-	videos, err := videoRepo.GetFromPlaylist(selectedPlaylistID, 10, 1, "", 1) 
+	videos, err := videoRepo.GetFromPlaylist(selectedPlaylistID, 10, 1, "", 1, false) 
 
 	if err != nil {
 		t.Fatalf("Error %s \n", err)
@@ -283,7 +283,7 @@ func TestGetFromPlaylist(t *testing.T) {
 	// Expect that 'like' search returns correct videos
 
 	search := "Bobo"
-	videos, err = videoRepo.GetFromPlaylist(selectedPlaylistID, 10, 1, search, 1) 
+	videos, err = videoRepo.GetFromPlaylist(selectedPlaylistID, 10, 1, search, 1, false) 
 
 	if err != nil {
 		t.Fatalf("Error %s \n", err)
@@ -295,7 +295,7 @@ func TestGetFromPlaylist(t *testing.T) {
 		}
 	}
 
-    videos, err = videoRepo.GetFromPlaylist(selectedPlaylistID, 10, 1, "undefined", 1) 
+    videos, err = videoRepo.GetFromPlaylist(selectedPlaylistID, 10, 1, "undefined", 1, false) 
 
 	if err != nil {
 		t.Fatalf("Error %s \n", err)
@@ -313,6 +313,7 @@ func TestGetFromPlaylist(t *testing.T) {
 		2,  // page
 		"", // search
 		1,  // sort
+		false, // source removed
 	) 
 
 	if err != nil {
@@ -334,6 +335,7 @@ func TestGetFromPlaylist(t *testing.T) {
 		2,  // page
 		"", // search
 		1,  // sort
+		false, // source removed
 	) 
 
     if videos[0].Title != "Steve" {
@@ -347,6 +349,7 @@ func TestGetFromPlaylist(t *testing.T) {
 		1,  // page
 		"", // search
 		1,  // sort
+		false, // source removed
 	) 
 
 	video1 := videos[0]
@@ -368,6 +371,7 @@ func TestGetFromPlaylist(t *testing.T) {
 		1,  // page
 		"", // search
 		0,  // sort
+		false, // source removed
 	)
 
 	// Parse into time.Time
@@ -403,6 +407,7 @@ func TestGetFromPlaylist(t *testing.T) {
 		1,  // page
 		"", // search
 		1,  // sort
+		false, // source removed
 	)
 
 	// Parse into time.Time
@@ -431,3 +436,194 @@ func TestGetFromPlaylist(t *testing.T) {
 		t.Errorf("Expected downloadDate order to be ascending")
 	}
 }
+
+func TestSetDownloadPaused(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := newTestRepo(t, db)
+
+	video := newVideo()
+	video.DownloadComplete = false
+	id, err := repo.Create(video)
+
+	if err != nil {
+		t.Fatalf("Failed to create video: %s\n", err)
+	}
+
+	err = repo.SetDownloadPaused(id, true)
+
+	if err != nil {
+		t.Fatalf("Error pausing download: %s\n", err)
+	}
+
+	pausedVideo, err := repo.Get(strconv.FormatInt(id, 10))
+
+	if err != nil {
+		t.Fatalf("Error getting video: %s\n", err)
+	}
+
+	if !pausedVideo.DownloadPaused {
+		t.Errorf("Expected video download to be paused")
+	}
+
+	err = repo.SetDownloadPaused(id, false)
+
+	if err != nil {
+		t.Fatalf("Error resuming download: %s\n", err)
+	}
+
+	resumedVideo, _ := repo.Get(strconv.FormatInt(id, 10))
+
+	if resumedVideo.DownloadPaused {
+		t.Errorf("Expected video download not to be paused")
+	}
+}
+
+func TestVideoMetadata(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := newTestRepo(t, db)
+
+	video := newVideo()
+	video.Uploader = "uploader"
+	video.Channel = "channel"
+	video.UploadDate = "20240101"
+	video.Tags = []string{"tag1", "tag2"}
+	video.ViewCount = 1000
+
+	id, err := repo.Create(video)
+
+	if err != nil {
+		t.Fatalf("Failed to create video: %s\n", err)
+	}
+
+	createdVideo, err := repo.Get(strconv.FormatInt(id, 10))
+
+	if err != nil {
+		t.Fatalf("Error getting video: %s\n", err)
+	}
+
+	if createdVideo.Uploader != "uploader" || createdVideo.UploadDate != "20240101" || createdVideo.ViewCount != 1000 {
+		t.Errorf("Expected metadata to be saved, got %+v", createdVideo)
+	}
+
+	if len(createdVideo.Tags) != 2 || createdVideo.Tags[1] != "tag2" {
+		t.Errorf("Expected tags [tag1 tag2], got %v", createdVideo.Tags)
+	}
+
+	createdVideo.Tags = nil
+	createdVideo.Description = "description"
+
+	err = repo.Update(*createdVideo)
+
+	if err != nil {
+		t.Fatalf("Error updating video: %s\n", err)
+	}
+
+	updatedVideo, _ := repo.Get(strconv.FormatInt(id, 10))
+
+	if updatedVideo.Description != "description" || len(updatedVideo.Tags) != 0 {
+		t.Errorf("Expected description to be updated and tags cleared, got %+v", updatedVideo)
+	}
+}
+
+func TestAvailability(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := newTestRepo(t, db)
+
+	checkedID, _ := repo.Create(newVideo("checked"))
+	uncheckedID, _ := repo.Create(newVideo("unchecked"))
+
+	err := repo.SetAvailability(checkedID, models.AvailabilityRemoved, 100)
+
+	if err != nil {
+		t.Fatalf("Error setting availability: %s\n", err)
+	}
+
+	videos, err := repo.GetAvailabilityDue(200, 10)
+
+	if err != nil {
+		t.Fatalf("Error getting videos due: %s\n", err)
+	}
+
+	if len(videos) != 2 || videos[0].ID != uncheckedID {
+		t.Errorf("Expected the unchecked video first, got %+v", videos)
+	}
+
+	videos, _ = repo.GetAvailabilityDue(100, 10)
+
+	if len(videos) != 1 || videos[0].ID != uncheckedID {
+		t.Errorf("Expected only the unchecked video to be due, got %+v", videos)
+	}
+
+	videos, err = repo.GetFromPlaylist(ALL_PLAYLIST_ID, 10, 1, "", 1, true)
+
+	if err != nil {
+		t.Fatalf("Error %s \n", err)
+	}
+
+	if len(videos) != 1 || videos[0].ID != checkedID || videos[0].Availability != models.AvailabilityRemoved {
+		t.Errorf("Expected only the removed video, got %+v", videos)
+	}
+}
+
+func TestGetExisting(t *testing.T) {
+	db := InitializeDB(t)
+	defer CleanupDB(t, db)
+
+	repo := newTestRepo(t, db)
+
+	video := newVideo()
+	video.Url = "https://www.youtube.com/watch?v=abc"
+	video.ExtractorKey = "Youtube"
+	video.SourceID = "abc"
+
+	id, err := repo.Create(video)
+
+	if err != nil {
+		t.Fatalf("Failed to create video: %s\n", err)
+	}
+
+	existingVideo, err := repo.GetExisting("https://youtu.be/abc", "Youtube", "abc")
+
+	if err != nil {
+		t.Fatalf("Error getting existing video: %s\n", err)
+	}
+
+	if existingVideo == nil || existingVideo.ID != id {
+		t.Errorf("Expected the video to be found by its source id, got %+v", existingVideo)
+	}
+
+	existingVideo, _ = repo.GetExisting("https://youtu.be/abc", "Vimeo", "abc")
+
+	if existingVideo != nil {
+		t.Errorf("Expected no video for another extractor, got %+v", existingVideo)
+	}
+
+	existingVideo, _ = repo.GetExisting(video.Url, "", "")
+
+	if existingVideo == nil || existingVideo.ID != id {
+		t.Errorf("Expected the video to be found by its url, got %+v", existingVideo)
+	}
+
+	duplicate := newVideo()
+	duplicate.Url = "https://youtu.be/abc"
+	duplicate.ExtractorKey = "Youtube"
+	duplicate.SourceID = "abc"
+
+	if _, err := repo.Create(duplicate); err == nil {
+		t.Errorf("Expected creating the same source id twice to fail")
+	}
+
+	// Videos without a source id are not unique
+	_, err = repo.Create(newVideo())
+	_, err2 := repo.Create(newVideo())
+
+	if err != nil || err2 != nil {
+		t.Errorf("Expected videos without a source id to be created, got %v %v", err, err2)
+	}
+}
@@ -1,136 +1,177 @@
-package routes
-
-import (
-	"bytes"
-	"embed"
-	"log"
-	"net/http"
-	"text/template"
-	"time"
-	"vidviewer/downloadManager"
-	"vidviewer/handlers"
-	"vidviewer/middleware"
-	"vidviewer/repository"
-
-	"github.com/gorilla/mux"
-	_ "modernc.org/sqlite"
-)
-
-var Router *mux.Router
-
-func Initialize(assets embed.FS, htmlFiles embed.FS, repositories *repository.Repositories, dm *downloadManager.DownloadManager ) (r *mux.Router) {
-	// Serve HTML files
-	var serveHtml = func(w http.ResponseWriter, r *http.Request) {
-		requestedPath := r.URL.Path
-		if requestedPath == "/" {
-			requestedPath = "/index.html" // Serve index.html for the root path
-		}
-		requestedPath = "build" + requestedPath
-
-		templateContent, err := htmlFiles.ReadFile(requestedPath)
-		if err != nil {
-			log.Printf("Failed to read HTML file %s: %s", requestedPath, err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		tpl, err := template.New("").Parse(string(templateContent))
-		if err != nil {
-			log.Printf("Failed to parse HTML file %s: %s", requestedPath, err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		w.Header().Set("Content-Type", "text/html")
-		w.WriteHeader(http.StatusOK)
-
-		if err := tpl.Execute(w, nil); err != nil {
-			log.Printf("Failed to render HTML template: %s", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-	}
-	// Serve js/css files
-	var serveAssets = func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		ext := vars["ext"]
-
-		requestedPath := r.URL.Path
-		filePath := "build" + requestedPath
-		assetContent, err := assets.ReadFile(filePath)
-
-		if err != nil {
-			log.Printf("Failed to read asset file %s: %s", requestedPath, err)
-			log.Println(filePath)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		var contentType string
-
-		if ext == "css" {
-			contentType = "text/css"
-		} else {
-			contentType = "application/javascript"
-		}
-
-		// Set the "Content-Type" header based on the file extension
-		w.Header().Set("Content-Type", contentType)
-
-		// Serve the file content using http.FileServer
-		http.ServeContent(w, r, filePath, time.Now(), bytes.NewReader(assetContent))
-    }
-
-    Router = mux.NewRouter()
-
-	// Middleware 
-	Router.Use(middleware.FfmpegYtdlpMiddleware)
-	Router.Use(middleware.ConfigMiddleware)
-	Router.Use(middleware.FilesMiddleware)
-	Router.Use(middleware.DBMiddleware)
-	Router.Use(middleware.WithRepositories(repositories))
-	Router.Use(middleware.WithDownloadManagerMiddleware(dm))
-
-	// Serve html files from build folder
-	Router.HandleFunc("/", serveHtml).Methods("GET")
-
-	// Serve assets from build folder 
-	Router.HandleFunc("/assets/{file}.{ext}", serveAssets).Methods("GET")
-
-    // Return thumbnail pictures
-	Router.HandleFunc("/images/{file_id}", handlers.GetImage).Methods("GET")
-
-	// Create a websocket connection 
-	Router.HandleFunc("/websocket", handlers.HandleWebSocket)
-
-	// Update config file
-	Router.HandleFunc("/config", handlers.UpdateConfig).Methods("PUT")
-	Router.HandleFunc("/config", handlers.GetConfig).Methods("GET")
-
-	// PLAYLISTS
-	Router.HandleFunc("/playlists", handlers.CreatePlaylist).Methods("POST")
-	Router.HandleFunc("/playlists", handlers.GetAllPlaylists).Methods("GET")
-	Router.HandleFunc("/playlists/{id}", handlers.UpdatePlaylist).Methods("PUT")
-	Router.HandleFunc("/playlists/{id}", handlers.DeletePlaylist).Methods("DELETE")
-
-	Router.HandleFunc("/video/{id}/playlists", handlers.GetVideoPlaylists).Methods("GET")
-
-	// PLAYLISTVIDEOS
-	Router.HandleFunc("/playlist_videos", handlers.DeletePlaylistVideo).Methods("DELETE")
-	Router.HandleFunc("/playlist_videos", handlers.CreatePlaylistVideo).Methods("POST")
-
-	// DOWNLOADS
-	Router.HandleFunc("/downloads/{video_id}", handlers.CancelDownload).Methods("DELETE")
-	Router.HandleFunc("/downloads/{video_id}", handlers.ResumeDownload).Methods("PATCH")
-
-	// VIDEOS 
-	Router.HandleFunc("/videos", handlers.CreateVideo).Methods("POST")
-	Router.HandleFunc("/videos/{id}", handlers.GetVideo).Methods("GET")
-	Router.HandleFunc("/videos/{id}", handlers.UpdateVideo).Methods("PUT")
-	Router.HandleFunc("/videos/{id}", handlers.DeleteVideo).Methods("DELETE")
-	Router.HandleFunc("/video_formats", handlers.GetVideoFormats).Methods("GET")
-
-	Router.HandleFunc("/playlist/{id}/videos", handlers.GetVideosFromPlaylist).Methods("GET")
-
-	return Router
-}
+package routes
+
+import (
+	"bytes"
+	"embed"
+	"log"
+	"net/http"
+	"text/template"
+	"time"
+	"vidviewer/availability"
+	"vidviewer/downloadManager"
+	"vidviewer/handlers"
+	"vidviewer/imports"
+	"vidviewer/middleware"
+	"vidviewer/repository"
+	"vidviewer/subscriptions"
+
+	"github.com/gorilla/mux"
+	_ "modernc.org/sqlite"
+)
+
+var Router *mux.Router
+
+func Initialize(assets embed.FS, htmlFiles embed.FS, repositories *repository.Repositories, dm *downloadManager.DownloadManager, im *imports.Manager, poller *subscriptions.Poller, checker *availability.Checker) (r *mux.Router) {
+	// Serve HTML files
+	var serveHtml = func(w http.ResponseWriter, r *http.Request) {
+		requestedPath := r.URL.Path
+		if requestedPath == "/" {
+			requestedPath = "/index.html" // Serve index.html for the root path
+		}
+		requestedPath = "build" + requestedPath
+
+		templateContent, err := htmlFiles.ReadFile(requestedPath)
+		if err != nil {
+			log.Printf("Failed to read HTML file %s: %s", requestedPath, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		tpl, err := template.New("").Parse(string(templateContent))
+		if err != nil {
+			log.Printf("Failed to parse HTML file %s: %s", requestedPath, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+
+		if err := tpl.Execute(w, nil); err != nil {
+			log.Printf("Failed to render HTML template: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	// Serve js/css files
+	var serveAssets = func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		ext := vars["ext"]
+
+		requestedPath := r.URL.Path
+		filePath := "build" + requestedPath
+		assetContent, err := assets.ReadFile(filePath)
+
+		if err != nil {
+			log.Printf("Failed to read asset file %s: %s", requestedPath, err)
+			log.Println(filePath)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var contentType string
+
+		if ext == "css" {
+			contentType = "text/css"
+		} else {
+			contentType = "application/javascript"
+		}
+
+		// Set the "Content-Type" header based on the file extension
+		w.Header().Set("Content-Type", contentType)
+
+		// Serve the file content using http.FileServer
+		http.ServeContent(w, r, filePath, time.Now(), bytes.NewReader(assetContent))
+    }
+
+    Router = mux.NewRouter()
+
+	// Middleware 
+	Router.Use(middleware.FfmpegYtdlpMiddleware)
+	Router.Use(middleware.ConfigMiddleware)
+	Router.Use(middleware.FilesMiddleware)
+	Router.Use(middleware.DBMiddleware)
+	Router.Use(middleware.WithRepositories(repositories))
+	Router.Use(middleware.WithDownloadManagerMiddleware(dm))
+	Router.Use(middleware.WithImportManager(im))
+	Router.Use(middleware.WithSubscriptionPoller(poller))
+	Router.Use(middleware.WithAvailabilityChecker(checker))
+
+	// Serve html files from build folder
+	Router.HandleFunc("/", serveHtml).Methods("GET")
+
+	// Serve assets from build folder 
+	Router.HandleFunc("/assets/{file}.{ext}", serveAssets).Methods("GET")
+
+    // Return thumbnail pictures
+	Router.HandleFunc("/images/{file_id}", handlers.GetImage).Methods("GET")
+
+	// Create a websocket connection 
+	Router.HandleFunc("/websocket", handlers.HandleWebSocket)
+
+	// Update config file
+	Router.HandleFunc("/config", handlers.UpdateConfig).Methods("PUT")
+	Router.HandleFunc("/config", handlers.GetConfig).Methods("GET")
+
+	// PLAYLISTS
+	Router.HandleFunc("/playlists", handlers.CreatePlaylist).Methods("POST")
+	Router.HandleFunc("/playlists", handlers.GetAllPlaylists).Methods("GET")
+	Router.HandleFunc("/playlists/{id}", handlers.UpdatePlaylist).Methods("PUT")
+	Router.HandleFunc("/playlists/{id}", handlers.DeletePlaylist).Methods("DELETE")
+
+	Router.HandleFunc("/video/{id}/playlists", handlers.GetVideoPlaylists).Methods("GET")
+
+	// PLAYLISTVIDEOS
+	Router.HandleFunc("/playlist_videos", handlers.DeletePlaylistVideo).Methods("DELETE")
+	Router.HandleFunc("/playlist_videos", handlers.CreatePlaylistVideo).Methods("POST")
+
+	// DOWNLOADS. GET /downloads/{id} is a record of the history,
+	// the other routes take the video id
+	Router.HandleFunc("/downloads", handlers.GetDownloads).Methods("GET")
+	Router.HandleFunc("/downloads/{id}", handlers.GetDownloadRecord).Methods("GET")
+	Router.HandleFunc("/downloads/{video_id}", handlers.CancelDownload).Methods("DELETE")
+	Router.HandleFunc("/downloads/{video_id}", handlers.ResumeDownload).Methods("PATCH")
+	Router.HandleFunc("/downloads/{video_id}/pause", handlers.PauseDownload).Methods("PATCH")
+	Router.HandleFunc("/downloads/{video_id}/stop", handlers.StopRecording).Methods("PATCH")
+
+	// SUBSCRIPTIONS
+	Router.HandleFunc("/subscriptions", handlers.GetSubscriptions).Methods("GET")
+	Router.HandleFunc("/subscriptions", handlers.CreateSubscription).Methods("POST")
+	Router.HandleFunc("/subscriptions/{id}", handlers.GetSubscription).Methods("GET")
+	Router.HandleFunc("/subscriptions/{id}", handlers.UpdateSubscription).Methods("PUT")
+	Router.HandleFunc("/subscriptions/{id}", handlers.DeleteSubscription).Methods("DELETE")
+	Router.HandleFunc("/subscriptions/{id}/check", handlers.CheckSubscription).Methods("POST")
+
+	// AUTH PROFILES
+	Router.HandleFunc("/auth-profiles", handlers.GetAuthProfiles).Methods("GET")
+	Router.HandleFunc("/auth-profiles", handlers.CreateAuthProfile).Methods("POST")
+	Router.HandleFunc("/auth-profiles/{name}", handlers.UpdateAuthProfile).Methods("PUT")
+	Router.HandleFunc("/auth-profiles/{name}", handlers.DeleteAuthProfile).Methods("DELETE")
+
+	// DISK IMPORTS, started by POST /videos
+	Router.HandleFunc("/imports/{id}", handlers.GetImport).Methods("GET")
+	Router.HandleFunc("/imports/{id}", handlers.CancelImport).Methods("DELETE")
+
+	// DOWNLOAD ARCHIVE
+	Router.HandleFunc("/archive", handlers.ExportArchive).Methods("GET")
+	Router.HandleFunc("/archive", handlers.ImportArchive).Methods("POST")
+
+	// VIDEOS 
+	Router.HandleFunc("/videos", handlers.CreateVideo).Methods("POST")
+	Router.HandleFunc("/videos/batch", handlers.CreateVideoBatch).Methods("POST")
+	Router.HandleFunc("/videos/{id}", handlers.GetVideo).Methods("GET")
+	Router.HandleFunc("/videos/{id}", handlers.UpdateVideo).Methods("PUT")
+	Router.HandleFunc("/videos/{id}", handlers.DeleteVideo).Methods("DELETE")
+	Router.HandleFunc("/videos/{id}/redownload", handlers.RedownloadVideo).Methods("POST")
+	Router.HandleFunc("/videos/{id}/subtitles", handlers.GetVideoSubtitles).Methods("GET")
+	Router.HandleFunc("/videos/{id}/subtitles/{language}", handlers.GetVideoSubtitle).Methods("GET")
+	Router.HandleFunc("/videos/{id}/chapters", handlers.GetVideoChapters).Methods("GET")
+	Router.HandleFunc("/videos/{id}/chapters", handlers.UpdateVideoChapters).Methods("PUT")
+	Router.HandleFunc("/videos/{id}/chapters.vtt", handlers.GetVideoChaptersVtt).Methods("GET")
+	Router.HandleFunc("/videos/{id}/sponsorblock_segments", handlers.GetVideoSponsorBlockSegments).Methods("GET")
+	Router.HandleFunc("/video_formats", handlers.GetVideoFormats).Methods("GET")
+
+	Router.HandleFunc("/playlist/{id}/videos", handlers.GetVideosFromPlaylist).Methods("GET")
+
+	return Router
+}
@@ -0,0 +1,206 @@
+package subscriptions
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"vidviewer/models"
+	"vidviewer/repository"
+	"vidviewer/ytdlp"
+)
+
+// How often the poller looks for subscriptions that are due
+const DefaultPollInterval = 1 * time.Minute
+
+// Checks subscribed channels and playlists for videos
+// that are not in the library yet
+type Poller struct {
+	repositories *repository.Repositories
+	// Called with the videos of a subscription that are not in the library
+	onNewVideos  func(subscription models.Subscription, videos []ytdlp.FlatInfo)
+	PollInterval time.Duration
+	once         sync.Once
+	mutex        sync.Mutex // only one check runs at a time
+}
+
+func NewPoller(repositories *repository.Repositories, onNewVideos func(subscription models.Subscription, videos []ytdlp.FlatInfo)) *Poller {
+	return &Poller{
+		repositories: repositories,
+		onNewVideos:  onNewVideos,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// Starts polling in the background, calling it again does nothing.
+// The repositories need a database connection before this is called.
+func (p *Poller) Start() {
+	p.once.Do(func() {
+		go func() {
+			ticker := time.NewTicker(p.PollInterval)
+			for ; true; <-ticker.C {
+				p.Poll()
+			}
+		}()
+	})
+}
+
+// Checks every subscription that is due
+func (p *Poller) Poll() {
+	// Checked again once yt-dlp is installed
+	if !ytdlp.IsInstalled() {
+		return
+	}
+
+	subscriptions, err := p.repositories.SubscriptionRepo.GetDue(time.Now().Unix())
+
+	if err != nil {
+		log.Println("Error getting subscriptions:", err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		_, err := p.Check(subscription)
+		if err != nil {
+			log.Println("Error checking subscription:", subscription.Url, err)
+		}
+	}
+}
+
+// Runs yt-dlp against the subscription url and passes the videos
+// that are not in the library to onNewVideos. A subscription that
+// needs a seed is seeded instead, no videos are queued.
+func (p *Poller) Check(subscription models.Subscription) ([]ytdlp.FlatInfo, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if subscription.NeedsSeed {
+		_, err := p.seed(subscription)
+		return []ytdlp.FlatInfo{}, err
+	}
+
+	videos, err := p.getVideos(subscription)
+
+	if err != nil {
+		return nil, err
+	}
+
+	newVideos := []ytdlp.FlatInfo{}
+
+	for _, video := range videos {
+		// In the channel or playlist when the subscription was created, or queued before
+		seen, err := p.repositories.SubscriptionRepo.HasEntry(subscription.ID, video.Url, video.IEKey, video.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if seen {
+			continue
+		}
+
+		// Downloaded before with yt-dlp, see ArchiveRepository
+		archived, err := p.repositories.ArchiveRepo.Has(video.IEKey, video.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if archived {
+			continue
+		}
+
+		existingVideo, err := p.repositories.VideoRepo.GetExisting(video.Url, video.IEKey, video.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if existingVideo == nil {
+			newVideos = append(newVideos, video)
+		}
+	}
+
+	if len(newVideos) > 0 {
+		log.Printf("subscription %s has %d new videos", subscription.Url, len(newVideos))
+		p.onNewVideos(subscription, newVideos)
+
+		// Queued once, even if the download fails or the video is deleted
+		err = p.repositories.SubscriptionRepo.AddEntries(newEntries(subscription, newVideos))
+
+		if err != nil {
+			return newVideos, err
+		}
+	}
+
+	return newVideos, nil
+}
+
+// Records the videos currently in the channel or playlist so only
+// the videos uploaded after it are queued. Returns the number of videos.
+// The subscription needs a seed until it succeeds, see Check.
+func (p *Poller) Seed(subscription models.Subscription) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.seed(subscription)
+}
+
+// p.mutex must be held
+func (p *Poller) seed(subscription models.Subscription) (int, error) {
+	videos, err := p.getVideos(subscription)
+
+	if err != nil {
+		return 0, err
+	}
+
+	err = p.repositories.SubscriptionRepo.AddEntries(newEntries(subscription, videos))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(videos), p.repositories.SubscriptionRepo.SetSeeded(subscription.ID, subscription.Url)
+}
+
+func newEntries(subscription models.Subscription, videos []ytdlp.FlatInfo) []models.SubscriptionEntry {
+	entries := []models.SubscriptionEntry{}
+
+	for _, video := range videos {
+		entries = append(entries, models.SubscriptionEntry{
+			SubscriptionID: subscription.ID,
+			Url:            video.Url,
+			ExtractorKey:   video.IEKey,
+			SourceID:       video.ID,
+		})
+	}
+
+	return entries
+}
+
+// The videos of the subscription's channel or playlist, marks
+// the subscription as checked. p.mutex must be held.
+func (p *Poller) getVideos(subscription models.Subscription) ([]ytdlp.FlatInfo, error) {
+	info, err := ytdlp.GetFlatInfo(subscription.Url)
+
+	// Set even on errors so a broken url is not retried every minute
+	p.repositories.SubscriptionRepo.SetLastChecked(subscription.ID, time.Now().Unix())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsPlaylist() {
+		return nil, errors.New("url is not a channel or playlist")
+	}
+
+	videos := []ytdlp.FlatInfo{}
+
+	for _, video := range ytdlp.GetPlaylistVideos(*info) {
+		if video.Url != "" {
+			videos = append(videos, video)
+		}
+	}
+
+	return videos, nil
+}
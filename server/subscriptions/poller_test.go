@@ -0,0 +1,180 @@
+package subscriptions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"vidviewer/models"
+	"vidviewer/repository"
+	"vidviewer/ytdlp"
+)
+
+// Prints the flat playlist JSON in $STUB_PLAYLIST
+const stubYtdlp = `#!/bin/sh
+cat "$STUB_PLAYLIST"
+`
+
+// Puts the stub first in PATH so the poller runs it instead of yt-dlp
+func useStubYtdlp(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "yt-dlp"), []byte(stubYtdlp), 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to write yt-dlp stub: %s\n", err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("STUB_PLAYLIST", filepath.Join(dir, "playlist.json"))
+}
+
+// Sets the videos the stub returns for the channel
+func setStubVideos(t *testing.T, ids ...string) {
+	entries := ""
+
+	for i, id := range ids {
+		if i > 0 {
+			entries += ","
+		}
+		entries += fmt.Sprintf(`{"_type":"url","ie_key":"Youtube","id":"%s","url":"https://www.youtube.com/watch?v=%s","title":"%s"}`, id, id, id)
+	}
+
+	playlist := fmt.Sprintf(`{"_type":"playlist","id":"channel","title":"Channel","entries":[%s]}`, entries)
+
+	err := os.WriteFile(os.Getenv("STUB_PLAYLIST"), []byte(playlist), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write playlist: %s\n", err)
+	}
+}
+
+func newTestPoller(t *testing.T) (*Poller, *[]ytdlp.FlatInfo, *models.Subscription) {
+	db := repository.InitializeTempDB(t)
+
+	repositories := repository.NewRepositories()
+	repositories.SetDB(db)
+
+	playlistID, err := repositories.PlaylistRepo.Create("Channel", "2024-01-01")
+	if err != nil {
+		t.Fatalf("Error creating playlist: %s", err)
+	}
+
+	subscription := models.Subscription{
+		Url:             "https://www.youtube.com/@channel",
+		PlaylistID:      playlistID,
+		Format:          "22",
+		IntervalMinutes: 60,
+	}
+
+	subscription.ID, err = repositories.SubscriptionRepo.Create(subscription)
+	if err != nil {
+		t.Fatalf("Error creating subscription: %s", err)
+	}
+
+	queued := []ytdlp.FlatInfo{}
+
+	poller := NewPoller(repositories, func(s models.Subscription, videos []ytdlp.FlatInfo) {
+		if s.PlaylistID != playlistID || s.Format != "22" {
+			t.Errorf("Expected the videos to be queued into playlist %d as 22, got %d %s", playlistID, s.PlaylistID, s.Format)
+		}
+		queued = append(queued, videos...)
+	})
+
+	return poller, &queued, &subscription
+}
+
+func ids(videos []ytdlp.FlatInfo) []string {
+	result := []string{}
+	for _, video := range videos {
+		result = append(result, video.ID)
+	}
+	return result
+}
+
+func TestCheck(t *testing.T) {
+	useStubYtdlp(t)
+	setStubVideos(t, "existing", "archived", "new1", "new2")
+
+	poller, queued, subscription := newTestPoller(t)
+	repositories := poller.repositories
+
+	_, err := repositories.VideoRepo.Create(models.Video{Title: "existing", Url: "https://www.youtube.com/watch?v=existing", FileID: "existing"})
+	if err != nil {
+		t.Fatalf("Error creating video: %s", err)
+	}
+
+	_, err = repositories.ArchiveRepo.Add([]models.ArchiveEntry{{Extractor: "youtube", SourceID: "archived"}})
+	if err != nil {
+		t.Fatalf("Error adding archive entry: %s", err)
+	}
+
+	newVideos, err := poller.Check(*subscription)
+	if err != nil {
+		t.Fatalf("Error checking subscription: %s", err)
+	}
+
+	if fmt.Sprint(ids(newVideos)) != "[new1 new2]" || fmt.Sprint(ids(*queued)) != "[new1 new2]" {
+		t.Errorf("Expected new1 and new2 to be queued, got %v %v", ids(newVideos), ids(*queued))
+	}
+
+	checked, _ := repositories.SubscriptionRepo.Get(fmt.Sprint(subscription.ID))
+	if checked.LastChecked == 0 {
+		t.Errorf("Expected the subscription to be marked as checked")
+	}
+
+	// Queued videos are recorded, a failed download is not queued again
+	newVideos, err = poller.Check(*subscription)
+	if err != nil || len(newVideos) != 0 {
+		t.Errorf("Expected no new videos on the second check, got %v %v", ids(newVideos), err)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	useStubYtdlp(t)
+	setStubVideos(t, "old1", "old2")
+
+	poller, queued, subscription := newTestPoller(t)
+	repositories := poller.repositories
+
+	subscription.NeedsSeed = true
+	if err := repositories.SubscriptionRepo.Update(*subscription); err != nil {
+		t.Fatalf("Error updating subscription: %s", err)
+	}
+
+	// The url is broken, the subscription still needs a seed
+	os.Remove(os.Getenv("STUB_PLAYLIST"))
+
+	if _, err := poller.Seed(*subscription); err == nil {
+		t.Fatalf("Expected the seed to fail")
+	}
+
+	if seeded, _ := repositories.SubscriptionRepo.Get(fmt.Sprint(subscription.ID)); !seeded.NeedsSeed {
+		t.Fatalf("Expected the subscription to need a seed after a failure")
+	}
+
+	// Checking a subscription that needs a seed seeds it
+	setStubVideos(t, "old1", "old2")
+
+	newVideos, err := poller.Check(*subscription)
+	if err != nil || len(newVideos) != 0 || len(*queued) != 0 {
+		t.Fatalf("Expected the subscription to be seeded without queueing, got %v %v", ids(newVideos), err)
+	}
+
+	seeded, _ := repositories.SubscriptionRepo.Get(fmt.Sprint(subscription.ID))
+	if seeded.NeedsSeed {
+		t.Fatalf("Expected the subscription to be seeded")
+	}
+
+	// Only the videos uploaded after the subscription was created are queued
+	setStubVideos(t, "new", "old1", "old2")
+
+	newVideos, err = poller.Check(*seeded)
+	if err != nil {
+		t.Fatalf("Error checking subscription: %s", err)
+	}
+
+	if fmt.Sprint(ids(newVideos)) != "[new]" || fmt.Sprint(ids(*queued)) != "[new]" {
+		t.Errorf("Expected only new to be queued, got %v %v", ids(newVideos), ids(*queued))
+	}
+}
@@ -20,8 +20,18 @@ const (
 	RootFolderNotFound   MessageType = "root_folder_not_found"
 	FfmpegNotFound       MessageType = "ffmpeg_not_found"
 	YtdlpNotFound        MessageType = "ytdlp_not_found"
+	ImportProgress       MessageType = "import_progress"
 )
 
+// Payload of the VideoDownloadFail message
+type VideoDownloadFailPayload struct {
+	VideoID int64  `json:"video_id"`
+	Title   string `json:"title"`
+	Url     string `json:"url"`
+	Reason  string `json:"reason"`  // eg. "private", see vidviewer/errors
+	Message string `json:"message"` // human readable reason
+}
+
 type Client struct {
 	Connection *websocket.Conn
 }
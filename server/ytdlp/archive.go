@@ -0,0 +1,58 @@
+package ytdlp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"vidviewer/models"
+)
+
+// The extractor name yt-dlp writes to archive files for an
+// extractor key, eg. "youtube" for "Youtube"
+func ArchiveExtractor(extractorKey string) string {
+	return strings.ToLower(extractorKey)
+}
+
+// Parses a yt-dlp --download-archive file, one "<extractor> <id>"
+// per line. Returns the entries and the number of invalid lines,
+// blank lines are ignored.
+func ParseArchive(r io.Reader) ([]models.ArchiveEntry, int, error) {
+	entries := []models.ArchiveEntry{}
+	invalid := 0
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 2 {
+			invalid++
+			continue
+		}
+
+		entries = append(entries, models.ArchiveEntry{
+			Extractor: ArchiveExtractor(fields[0]),
+			SourceID:  fields[1],
+		})
+	}
+
+	return entries, invalid, scanner.Err()
+}
+
+// Writes the entries in the yt-dlp --download-archive format
+func WriteArchive(w io.Writer, entries []models.ArchiveEntry) error {
+	for _, entry := range entries {
+		_, err := fmt.Fprintf(w, "%s %s\n", entry.Extractor, entry.SourceID)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
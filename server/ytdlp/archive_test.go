@@ -0,0 +1,54 @@
+package ytdlp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"vidviewer/models"
+)
+
+func TestParseArchive(t *testing.T) {
+	archive := "youtube dQw4w9WgXcQ\n\nVimeo 76979871\r\ninvalid\nyoutube a b\n"
+
+	entries, invalid, err := ParseArchive(strings.NewReader(archive))
+
+	if err != nil {
+		t.Fatalf("Error parsing archive: %s\n", err)
+	}
+
+	if invalid != 2 {
+		t.Errorf("Expected 2 invalid lines, got %d", invalid)
+	}
+
+	expected := []models.ArchiveEntry{
+		{Extractor: "youtube", SourceID: "dQw4w9WgXcQ"},
+		{Extractor: "vimeo", SourceID: "76979871"},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), entries)
+	}
+
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("Expected entry %+v, got %+v", expected[i], entries[i])
+		}
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	var buffer bytes.Buffer
+
+	err := WriteArchive(&buffer, []models.ArchiveEntry{
+		{Extractor: "youtube", SourceID: "dQw4w9WgXcQ"},
+		{Extractor: "vimeo", SourceID: "76979871"},
+	})
+
+	if err != nil {
+		t.Fatalf("Error writing archive: %s\n", err)
+	}
+
+	if buffer.String() != "youtube dQw4w9WgXcQ\nvimeo 76979871\n" {
+		t.Errorf("Unexpected archive %q", buffer.String())
+	}
+}
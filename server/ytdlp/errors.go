@@ -0,0 +1,67 @@
+package ytdlp
+
+import (
+	"strings"
+	customErrors "vidviewer/errors"
+)
+
+// Messages yt-dlp (and the sites) print for each failure reason.
+// Checked in order, eg. "Video unavailable. This video is private"
+// is private and "Unable to download webpage: HTTP Error 429" is
+// rate limited, not a network error.
+var errorPatterns = []struct {
+	patterns []string
+	newError func() *customErrors.Error
+}{
+	{[]string{"private video", "video is private", "this video is private"}, customErrors.VideoPrivateError},
+	{[]string{"confirm your age", "age-restricted", "age restricted", "inappropriate for some users"}, customErrors.AgeRestrictedError},
+	{[]string{"available in your country", "geo restriction", "geo-restricted", "geo restricted", "not available from your location"}, customErrors.GeoBlockedError},
+	{[]string{"http error 429", "too many requests", "rate-limit", "rate limit"}, customErrors.RateLimitedError},
+	{[]string{"requested format is not available", "requested format not available", "no video formats found"}, customErrors.FormatUnavailableError},
+	{[]string{"video unavailable", "has been removed", "http error 404", "http error 410", "does not exist", "account associated with this video has been terminated", "no longer available"}, customErrors.VideoRemovedError},
+	{[]string{"unable to download webpage", "connection refused", "connection reset", "timed out", "name resolution", "network is unreachable", "no route to host", "urlopen error", "unable to connect"}, customErrors.NetworkError},
+}
+
+// Classifies a failed yt-dlp command by the last ERROR line of its
+// stderr, the WARNING and retry lines before it are not the reason
+// it failed. The whole stderr is used if there is no ERROR line.
+// Returns nil if err is nil.
+func ClassifyError(err error, stderr string) error {
+	if err == nil {
+		return nil
+	}
+
+	message := lastErrorLine(stderr)
+
+	output := strings.ToLower(message)
+	if message == "" {
+		output = strings.ToLower(stderr)
+	}
+
+	for _, errorPattern := range errorPatterns {
+		for _, pattern := range errorPattern.patterns {
+			if strings.Contains(output, pattern) {
+				return errorPattern.newError()
+			}
+		}
+	}
+
+	if message == "" {
+		message = err.Error()
+	}
+
+	return customErrors.DownloadFailedError(message)
+}
+
+// The last "ERROR: ..." line yt-dlp printed, without the prefix
+func lastErrorLine(stderr string) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); strings.HasPrefix(line, "ERROR:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "ERROR:"))
+		}
+	}
+
+	return ""
+}
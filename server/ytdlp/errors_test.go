@@ -0,0 +1,49 @@
+package ytdlp
+
+import (
+	"errors"
+	"testing"
+	customErrors "vidviewer/errors"
+)
+
+func TestClassifyError(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	cases := map[string]string{
+		"ERROR: [youtube] abc: Video unavailable. This video is private":                                               customErrors.ReasonPrivate,
+		"ERROR: [youtube] abc: Video unavailable. This video has been removed by the uploader":                         customErrors.ReasonRemoved,
+		"ERROR: [youtube] abc: The uploader has not made this video available in your country":                         customErrors.ReasonGeoBlocked,
+		"ERROR: [youtube] abc: Sign in to confirm your age. This video may be inappropriate for some users":            customErrors.ReasonAgeRestricted,
+		"ERROR: [youtube] abc: Unable to download webpage: HTTP Error 429: Too Many Requests":                          customErrors.ReasonRateLimited,
+		"ERROR: [youtube] abc: Requested format is not available. Use --list-formats":                                  customErrors.ReasonFormatUnavailable,
+		"ERROR: [generic] Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>": customErrors.ReasonNetwork,
+		"WARNING: something\nERROR: Postprocessing: Conversion failed!":                                                customErrors.ReasonUnknown,
+	}
+
+	for stderr, reason := range cases {
+		err := ClassifyError(exitErr, stderr)
+
+		var classified *customErrors.Error
+		if !errors.As(err, &classified) || classified.Reason != reason {
+			t.Errorf("Expected reason %s for %q, got %v", reason, stderr, err)
+		}
+	}
+
+	if err := ClassifyError(exitErr, "WARNING: something\nERROR: Postprocessing: Conversion failed!"); err.Error() != "Postprocessing: Conversion failed!" {
+		t.Errorf("Expected the yt-dlp error message, got %q", err)
+	}
+
+	// Rate limited and retried, then failed because the video was removed
+	stderr := "WARNING: [youtube] abc: HTTP Error 429: Too Many Requests. Retrying (1/3)...\n" +
+		"WARNING: [youtube] abc: Unable to download webpage: The read operation timed out. Retrying (2/3)...\n" +
+		"ERROR: [youtube] abc: Video unavailable. This video has been removed by the uploader"
+
+	var classified *customErrors.Error
+	if err := ClassifyError(exitErr, stderr); !errors.As(err, &classified) || classified.Reason != customErrors.ReasonRemoved {
+		t.Errorf("Expected the ERROR line to be classified, not the warnings, got %v", err)
+	}
+
+	if ClassifyError(nil, "ERROR: Private video") != nil {
+		t.Error("Expected nil for a successful command")
+	}
+}
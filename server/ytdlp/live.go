@@ -0,0 +1,54 @@
+package ytdlp
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// Live download modes, record the stream from the moment
+// the download starts or from the start of the stream
+const (
+	LiveFromNow   = "now"
+	LiveFromStart = "start"
+)
+
+// live_status of a stream that is being broadcast
+const liveStatusIsLive = "is_live"
+
+// How long yt-dlp has to finalize the file after
+// being interrupted before the process is killed
+const liveStopTimeout = 30 * time.Second
+
+func IsLiveMode(mode string) bool {
+	return mode == LiveFromNow || mode == LiveFromStart
+}
+
+func (info VideoInfo) IsLive() bool {
+	return info.LiveStatus == liveStatusIsLive
+}
+
+// Records a live stream until it ends or ctx is cancelled. Cancelling
+// interrupts yt-dlp (like Ctrl+C) so it stops recording and finalizes
+// the file instead of being killed.
+func CreateLiveDownloadCommand(ctx context.Context, url string, format string, mode string, filePath string) *exec.Cmd {
+	// Live streams are mostly single HLS/DASH formats with audio
+	if format == "" {
+		format = "best"
+	} else {
+		format = format + "+bestaudio/best"
+	}
+
+	args := []string{"--no-playlist", "-f", format, "-o", filePath}
+
+	if mode == LiveFromStart {
+		args = append(args, "--live-from-start")
+	}
+
+	args = append(append(args, progressTemplateArgs...), authArgs(url)...)
+
+	cmd := exec.CommandContext(ctx, "yt-dlp", append(args, url)...)
+	interruptOnCancel(cmd, liveStopTimeout)
+
+	return cmd
+}
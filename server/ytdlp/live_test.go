@@ -0,0 +1,41 @@
+package ytdlp
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCreateLiveDownloadCommand(t *testing.T) {
+	cmd := CreateLiveDownloadCommand(context.Background(), "https://example.com/live", "", LiveFromStart, "/tmp/video.mp4")
+	args := strings.Join(cmd.Args, " ")
+
+	for _, expected := range []string{"-f best ", "--live-from-start", "-o /tmp/video.mp4", "https://example.com/live"} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in %q", expected, args)
+		}
+	}
+
+	if cmd.Cancel == nil {
+		t.Errorf("Expected the command to be interrupted instead of killed")
+	}
+
+	cmd = CreateLiveDownloadCommand(context.Background(), "https://example.com/live", "301", LiveFromNow, "/tmp/video.mp4")
+	args = strings.Join(cmd.Args, " ")
+
+	if strings.Contains(args, "--live-from-start") || !strings.Contains(args, "-f 301+bestaudio/best") {
+		t.Errorf("Unexpected arguments %q", args)
+	}
+}
+
+func TestParseLiveProgressLine(t *testing.T) {
+	progress, ok := ParseProgressLine(`[vidviewer-download] {"status": "downloading", "downloaded_bytes": 5242880, "total_bytes": null, "speed": 262144.0, "eta": null, "elapsed": 20.5}`)
+
+	if !ok {
+		t.Fatal("Expected a progress line")
+	}
+
+	if progress.Percent != 0 || progress.DownloadedBytes != 5242880 || progress.Elapsed != 20.5 {
+		t.Errorf("Unexpected progress %+v", progress)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package ytdlp
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Starts the command in its own process group, so a Ctrl-C in the
+// terminal only reaches the server, which then stops the command
+func detachFromTerminal(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,122 @@
+package ytdlp
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+// Download phases
+const (
+	PhaseDownloading         = "downloading"
+	PhaseMerging             = "merging"
+	PhaseExtractingThumbnail = "extracting_thumbnail"
+	PhaseFinalizing          = "finalizing"
+	// Downloading a live stream, there is no total size or eta
+	PhaseRecording = "recording"
+)
+
+// Progress of a download, parsed from the --progress-template lines
+type Progress struct {
+	Phase           string `json:"phase"`
+	Percent         uint   `json:"percent"`
+	DownloadedBytes int64  `json:"downloaded_bytes"`
+	// Estimated for fragmented downloads, 0 if unknown
+	TotalBytes int64   `json:"total_bytes"`
+	Speed      float64 `json:"speed"` // bytes per second
+	Eta        int64   `json:"eta"`   // seconds, 0 if unknown
+	// Set for fragmented (eg. HLS, DASH) downloads
+	FragmentIndex int `json:"fragment_index"`
+	FragmentCount int `json:"fragment_count"`
+	// Seconds since the download started, 0 if unknown
+	Elapsed float64 `json:"elapsed"`
+}
+
+// Prefixes of the lines written by the progress templates
+const (
+	downloadProgressPrefix    = "[vidviewer-download] "
+	postprocessProgressPrefix = "[vidviewer-postprocess] "
+)
+
+// Makes yt-dlp print the progress dicts as JSON, one per line
+var progressTemplateArgs = []string{
+	"--newline",
+	"--progress-template", "download:" + downloadProgressPrefix + "%(progress)j",
+	"--progress-template", "postprocess:" + postprocessProgressPrefix + "%(progress)j",
+}
+
+// yt-dlp progress dict, numbers can be floats or null
+type progressTemplate struct {
+	Status             string  `json:"status"`
+	DownloadedBytes    float64 `json:"downloaded_bytes"`
+	TotalBytes         float64 `json:"total_bytes"`
+	TotalBytesEstimate float64 `json:"total_bytes_estimate"`
+	Speed              float64 `json:"speed"`
+	Eta                float64 `json:"eta"`
+	FragmentIndex      int     `json:"fragment_index"`
+	FragmentCount      int     `json:"fragment_count"`
+	Elapsed            float64 `json:"elapsed"`
+	Postprocessor      string  `json:"postprocessor"`
+}
+
+// Parses a line printed with the progress templates,
+// returns false for any other output
+func ParseProgressLine(line string) (Progress, bool) {
+	line = strings.TrimSpace(line)
+
+	isDownload := strings.HasPrefix(line, downloadProgressPrefix)
+	isPostprocess := strings.HasPrefix(line, postprocessProgressPrefix)
+
+	if !isDownload && !isPostprocess {
+		return Progress{}, false
+	}
+
+	data := strings.TrimPrefix(strings.TrimPrefix(line, downloadProgressPrefix), postprocessProgressPrefix)
+
+	template := progressTemplate{}
+	if err := json.Unmarshal([]byte(data), &template); err != nil {
+		return Progress{}, false
+	}
+
+	if isPostprocess {
+		return Progress{Phase: postprocessorPhase(template.Postprocessor), Percent: 100}, true
+	}
+
+	progress := Progress{
+		Phase:           PhaseDownloading,
+		DownloadedBytes: int64(template.DownloadedBytes),
+		TotalBytes:      int64(template.TotalBytes),
+		Speed:           template.Speed,
+		Eta:             int64(template.Eta),
+		FragmentIndex:   template.FragmentIndex,
+		FragmentCount:   template.FragmentCount,
+		Elapsed:         template.Elapsed,
+	}
+
+	if progress.TotalBytes == 0 {
+		progress.TotalBytes = int64(template.TotalBytesEstimate)
+	}
+
+	switch {
+	case template.Status == "finished":
+		progress.Percent = 100
+	case progress.TotalBytes > 0:
+		progress.Percent = uint(math.Min(100, float64(progress.DownloadedBytes)*100/float64(progress.TotalBytes)))
+	case progress.FragmentCount > 0:
+		progress.Percent = uint(math.Min(100, float64(progress.FragmentIndex)*100/float64(progress.FragmentCount)))
+	}
+
+	return progress, true
+}
+
+// Phase of a yt-dlp postprocessor, by its key
+func postprocessorPhase(postprocessor string) string {
+	switch {
+	case postprocessor == "Merger":
+		return PhaseMerging
+	case strings.Contains(postprocessor, "Thumbnail"):
+		return PhaseExtractingThumbnail
+	default:
+		return PhaseFinalizing
+	}
+}
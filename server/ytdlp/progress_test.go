@@ -0,0 +1,56 @@
+package ytdlp
+
+import "testing"
+
+func TestParseProgressLine(t *testing.T) {
+	progress, ok := ParseProgressLine(`[vidviewer-download] {"status": "downloading", "downloaded_bytes": 1048576, "total_bytes": 4194304, "speed": 524288.5, "eta": 6, "filename": "video.mp4"}`)
+
+	if !ok {
+		t.Fatal("Expected a progress line")
+	}
+
+	expected := Progress{Phase: PhaseDownloading, Percent: 25, DownloadedBytes: 1048576, TotalBytes: 4194304, Speed: 524288.5, Eta: 6}
+	if progress != expected {
+		t.Errorf("Expected %+v, got %+v", expected, progress)
+	}
+}
+
+func TestParseFragmentedProgressLine(t *testing.T) {
+	progress, ok := ParseProgressLine(`[vidviewer-download] {"status": "downloading", "downloaded_bytes": 2000, "total_bytes": null, "total_bytes_estimate": 10000.0, "speed": null, "eta": null, "fragment_index": 3, "fragment_count": 15}`)
+
+	if !ok {
+		t.Fatal("Expected a progress line")
+	}
+
+	if progress.Percent != 20 || progress.TotalBytes != 10000 || progress.FragmentIndex != 3 || progress.FragmentCount != 15 || progress.Speed != 0 {
+		t.Errorf("Unexpected progress %+v", progress)
+	}
+}
+
+func TestParsePostprocessProgressLine(t *testing.T) {
+	phases := map[string]string{
+		"Merger":                    PhaseMerging,
+		"FFmpegThumbnailsConvertor": PhaseExtractingThumbnail,
+		"MoveFiles":                 PhaseFinalizing,
+	}
+
+	for postprocessor, phase := range phases {
+		progress, ok := ParseProgressLine(`[vidviewer-postprocess] {"status": "started", "postprocessor": "` + postprocessor + `"}`)
+
+		if !ok || progress.Phase != phase {
+			t.Errorf("Expected phase %s for %s, got %+v", phase, postprocessor, progress)
+		}
+	}
+}
+
+func TestParseOtherLines(t *testing.T) {
+	for _, line := range []string{
+		"[youtube] abc: Downloading webpage",
+		"[download]  12.5% of 10.00MiB at 1.00MiB/s ETA 00:09",
+		"[vidviewer-download] not json",
+	} {
+		if _, ok := ParseProgressLine(line); ok {
+			t.Errorf("Expected %q not to be a progress line", line)
+		}
+	}
+}
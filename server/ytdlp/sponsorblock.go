@@ -0,0 +1,91 @@
+package ytdlp
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// SponsorBlock modes, segments are stored as skip ranges
+// with "mark" and cut from the file with "remove"
+const (
+	SponsorBlockMark   = "mark"
+	SponsorBlockRemove = "remove"
+)
+
+var SponsorBlockModes = []string{SponsorBlockMark, SponsorBlockRemove}
+
+// Categories cut from the file in "remove" mode. Music off-topic,
+// filler and previews are kept as they are often part of the video.
+var SponsorBlockRemoveCategories = []string{"sponsor", "selfpromo", "interaction", "intro", "outro"}
+
+func IsSponsorBlockMode(mode string) bool {
+	for _, sponsorBlockMode := range SponsorBlockModes {
+		if sponsorBlockMode == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// A segment from the SponsorBlock API, as added to
+// the yt-dlp metadata by its SponsorBlock postprocessor
+type SponsorBlockSegment struct {
+	Category  string  `json:"category"`
+	Type      string  `json:"type"` // skip, mute, poi or chapter
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
+
+// Metadata written after the download, in "remove" mode
+// the chapters are the ones left after cutting the segments
+type SponsorBlockInfo struct {
+	Segments []SponsorBlockSegment `json:"sponsorblock_chapters"`
+	Chapters []Chapter             `json:"chapters"`
+}
+
+// File the SponsorBlock metadata of a download is written to
+func SponsorBlockInfoPath(filePath string) string {
+	return filePath + ".sponsorblock.json"
+}
+
+// Adds the SponsorBlock options to a yt-dlp download command. The
+// segments are written to SponsorBlockInfoPath(filePath) once the
+// file is complete. In "mark" mode the file is not modified.
+func SetSponsorBlock(cmd *exec.Cmd, mode string, filePath string) {
+	args := []string{cmd.Args[0]}
+
+	switch mode {
+	case SponsorBlockMark:
+		args = append(args, "--sponsorblock-mark", "all", "--no-embed-chapters")
+	case SponsorBlockRemove:
+		args = append(args, "--sponsorblock-remove", strings.Join(SponsorBlockRemoveCategories, ","))
+	default:
+		return
+	}
+
+	args = append(args, "--print-to-file", "after_move:%(.{sponsorblock_chapters,chapters})j", SponsorBlockInfoPath(filePath))
+
+	cmd.Args = append(args, cmd.Args[1:]...)
+}
+
+// yt-dlp appends to the file, the last line is used
+func ReadSponsorBlockInfo(path string) (*SponsorBlockInfo, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	info := SponsorBlockInfo{}
+	err = json.Unmarshal([]byte(lines[len(lines)-1]), &info)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,47 @@
+package ytdlp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetSponsorBlock(t *testing.T) {
+	cmd := CreateDownloadCommand(context.Background(), "https://www.youtube.com/watch?v=abc", "", "/tmp/video.mp4")
+	SetSponsorBlock(cmd, SponsorBlockRemove, "/tmp/video.mp4")
+
+	args := strings.Join(cmd.Args, " ")
+
+	if !strings.Contains(args, "--sponsorblock-remove sponsor,selfpromo,interaction,intro,outro") {
+		t.Errorf("Expected the remove option, got %s", args)
+	}
+
+	if !strings.Contains(args, "/tmp/video.mp4.sponsorblock.json") || cmd.Args[len(cmd.Args)-1] != "https://www.youtube.com/watch?v=abc" {
+		t.Errorf("Expected the info file and the url last, got %s", args)
+	}
+}
+
+func TestReadSponsorBlockInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.mp4.sponsorblock.json")
+
+	// Written twice, eg. by a download that was retried
+	os.WriteFile(path, []byte(`{"sponsorblock_chapters": null, "chapters": null}
+{"sponsorblock_chapters": [{"category": "sponsor", "type": "skip", "start_time": 10.5, "end_time": 30, "title": "Sponsor"}], "chapters": [{"title": "Intro", "start_time": 0, "end_time": 10.5}]}
+`), 0644)
+
+	info, err := ReadSponsorBlockInfo(path)
+
+	if err != nil {
+		t.Fatalf("Error reading info: %s", err)
+	}
+
+	if len(info.Segments) != 1 || info.Segments[0] != (SponsorBlockSegment{Category: "sponsor", Type: "skip", StartTime: 10.5, EndTime: 30}) {
+		t.Errorf("Unexpected segments %+v", info.Segments)
+	}
+
+	if len(info.Chapters) != 1 || info.Chapters[0].Title != "Intro" {
+		t.Errorf("Unexpected chapters %+v", info.Chapters)
+	}
+}
@@ -3,55 +3,154 @@ package ytdlp
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"math"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+	"vidviewer/config"
 )
 
 type Format struct {
-	FormatID string `json:"format_id"`
-	Ext      string `json:"ext"`
-	Resolution      string `json:"resolution"`
-	Filesize string `json:"filesize"`
+	FormatID       string  `json:"format_id"`
+	Ext            string  `json:"ext"`
+	Resolution     string  `json:"resolution"`
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	Fps            float64 `json:"fps"`
+	Vcodec         string  `json:"vcodec"`
+	Acodec         string  `json:"acodec"`
+	Filesize       int64   `json:"filesize"`
+	FilesizeApprox int64   `json:"filesize_approx"`
+	Tbr            float64 `json:"tbr"`
+	FormatNote     string  `json:"format_note"`
+	AudioOnly      bool    `json:"audio_only"`
+	VideoOnly      bool    `json:"video_only"`
 }
 
-// Get the various format ids and their resolutions 
-// Run yt-dlp --list-formats (url) to see the output in your terminal
-// Currently only mp4 extensions are returned
-func GetFormats(url string) ([]Format, error) {
-	cmd := exec.Command("yt-dlp", "--list-formats", url)
+// Metadata of a single video, from yt-dlp --dump-single-json
+type VideoInfo struct {
+	ID           string    `json:"id"`
+	// The extractor's name, eg. "Youtube", ID is only unique for one extractor
+	ExtractorKey string    `json:"extractor_key"`
+	Title        string    `json:"title"`
+	Duration     float64   `json:"duration"`
+	Uploader     string    `json:"uploader"`
+	Channel      string    `json:"channel"`
+	UploadDate   string    `json:"upload_date"` // YYYYMMDD
+	Description  string    `json:"description"`
+	Tags         []string  `json:"tags"`
+	ViewCount    int64     `json:"view_count"`
+	WebpageUrl   string    `json:"webpage_url"`
+	LiveStatus   string    `json:"live_status"` // is_live, is_upcoming, was_live, not_live
+	Formats      []Format  `json:"formats"`
+	Chapters     []Chapter `json:"chapters"`
+}
+
+type Chapter struct {
+	Title     string  `json:"title"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
+
+// Options passing the auth profile matching the url to yt-dlp
+func authArgs(url string) []string {
+	profile := config.MatchAuthProfile(url)
+
+	if profile == nil {
+		return []string{}
+	}
 
-	output, err := cmd.Output()
+	if profile.Type == config.AuthProfileNetrc {
+		return []string{"--netrc", "--netrc-location", profile.SecretPath()}
+	}
+
+	return []string{"--cookies", profile.SecretPath()}
+}
+
+// Runs yt-dlp --dump-single-json and returns its output
+func dumpSingleJSON(url string, args ...string) ([]byte, error) {
+	args = append(append(append([]string{"--dump-single-json"}, authArgs(url)...), args...), url)
+	cmd := exec.Command("yt-dlp", args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		log.Println("Error executing command:", err)
+		log.Println("Command output (stderr):", stderr.String())
+		return nil, ClassifyError(err, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func parseVideoInfo(data []byte) (*VideoInfo, error) {
+	info := VideoInfo{}
+	err := json.Unmarshal(data, &info)
 
 	if err != nil {
 		return nil, err
 	}
 
-	lines := strings.Split(string(output), "\n")
+	// yt-dlp uses "none" for a missing stream
+	for i := range info.Formats {
+		info.Formats[i].AudioOnly = info.Formats[i].Vcodec == "none"
+		info.Formats[i].VideoOnly = info.Formats[i].Acodec == "none"
+	}
 
-	formats := []Format{}
+	return &info, nil
+}
 
-	// Iterate through each line
-	for _, line := range lines {
-		if strings.Contains(line, "|") {
-			fields := strings.Fields(line)
+func GetVideoInfo(url string) (*VideoInfo, error) {
+	data, err := dumpSingleJSON(url, "--no-playlist")
 
-			// currently only return mp4 
-			if fields[1] != "mp4" {
-				continue
-			}
-				
-			format := Format{
-				FormatID:   fields[0],
-				Ext:        fields[1],
-				Resolution: fields[2],
-			}
-			formats = append(formats, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseVideoInfo(data)
+}
+
+// Probes the url without downloading anything.
+// Returns nil if the video can still be downloaded,
+// otherwise the classified yt-dlp error.
+func CheckAvailability(url string) error {
+	args := append(append([]string{"--simulate", "--no-playlist", "--no-warnings", "--quiet"}, authArgs(url)...), url)
+	cmd := exec.Command("yt-dlp", args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	return ClassifyError(cmd.Run(), stderr.String())
+}
+
+// Get the formats of the video.
+// Currently only mp4 extensions are returned
+func GetFormats(url string) ([]Format, error) {
+	info, err := GetVideoInfo(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	formats := []Format{}
+
+	for _, format := range info.Formats {
+		// currently only return mp4 
+		if format.Ext != "mp4" {
+			continue
 		}
+
+		formats = append(formats, format)
 	}
 
 	return formats, nil
@@ -59,12 +158,13 @@ func GetFormats(url string) ([]Format, error) {
 
 func DownloadVideoThumbnail(videoURL, outputPath string) error {
     // Run the yt-dlp command to extract the thumbnail
-    cmd := exec.Command("yt-dlp", "--write-thumbnail", "--skip-download", "--convert-thumbnails", "jpg",  "-o", outputPath, videoURL)
+    args := append([]string{"--write-thumbnail", "--skip-download", "--convert-thumbnails", "jpg",  "-o", outputPath}, authArgs(videoURL)...)
+    cmd := exec.Command("yt-dlp", append(args, videoURL)...)
 
     output, err := cmd.CombinedOutput()
     if err != nil {
         fmt.Printf("Command's output:\n%s\n", string(output))
-        return err
+        return ClassifyError(err, string(output))
     }
     
     outputStr := string(output)
@@ -75,44 +175,98 @@ func DownloadVideoThumbnail(videoURL, outputPath string) error {
     return nil 
 }
 
-func ExtractVideoInfo(url string) (string, string, error) {
-    cmd := exec.Command("yt-dlp", "--get-duration", "--get-title", url)
+// Output of yt-dlp --flat-playlist --dump-single-json.
+// Playlists and channels have the type "playlist" and list their
+// videos in Entries without extracting each video.
+type FlatInfo struct {
+	Type     string     `json:"_type"`
+	ID       string     `json:"id"`
+	IEKey    string     `json:"ie_key"`
+	Url      string     `json:"url"`
+	Title    string     `json:"title"`
+	Duration float64    `json:"duration"`
+	Entries  []FlatInfo `json:"entries"`
+	raw      []byte
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+func (info FlatInfo) IsPlaylist() bool {
+	return info.Type == "playlist"
+}
+
+// How many levels of nested playlists are expanded,
+// eg. channel -> tab (videos, shorts, live) -> videos
+const maxPlaylistDepth = 2
+
+// --no-playlist only applies to urls of a video in a playlist, eg.
+// watch?v=X&list=Y returns the video. Playlist urls are expanded.
+func GetFlatInfo(url string) (*FlatInfo, error) {
+	data, err := dumpSingleJSON(url, "--flat-playlist", "--no-playlist")
 
-	err := cmd.Run()
 	if err != nil {
-		log.Println("Error executing command:", err)
-		log.Println("Command output (stderr):", stderr.String())
+		return nil, err
 	}
 
-	info := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	info := FlatInfo{raw: data}
+	err = json.Unmarshal(data, &info)
 
-	title := ""
-	duration :=  ""
-
-	if (len(info) > 0) {
-		title = strings.TrimSpace(info[0])
-	} 
-	
-	if (len(info) > 1 ) {
-		duration = strings.TrimSpace(info[1])
+	if err != nil {
+		return nil, err
 	}
 
-	if duration == "" {
-		log.Println("Failed to extract video duration")
+	return &info, nil
+}
+
+// --flat-playlist only affects playlists, so the output for a
+// single video is already its full metadata
+func (info FlatInfo) VideoInfo() (*VideoInfo, error) {
+	if info.IsPlaylist() || info.raw == nil {
+		return nil, errors.New("not a single video")
 	}
 
-	if title == "" {
-		log.Println("Failed to extract video title")
+	return parseVideoInfo(info.raw)
+}
+
+// Returns the videos of a playlist or channel.
+// Nested playlists (eg. the tabs of a channel) are expanded.
+func GetPlaylistVideos(info FlatInfo) []FlatInfo {
+	return expandEntries(info.Entries, 0)
+}
+
+func expandEntries(entries []FlatInfo, depth int) []FlatInfo {
+	videos := []FlatInfo{}
+
+	for _, entry := range entries {
+		isNested := entry.IsPlaylist() || strings.HasSuffix(entry.IEKey, "Tab") || strings.HasSuffix(entry.IEKey, "Playlist")
+
+		if !isNested {
+			if entry.Url != "" {
+				videos = append(videos, entry)
+			}
+			continue
+		}
+
+		if depth >= maxPlaylistDepth {
+			continue
+		}
+
+		if len(entry.Entries) > 0 {
+			videos = append(videos, expandEntries(entry.Entries, depth+1)...)
+			continue
+		}
+
+		nested, err := GetFlatInfo(entry.Url)
+		if err != nil {
+			log.Println("Error getting nested playlist:", entry.Url, err)
+			continue
+		}
+		videos = append(videos, expandEntries(nested.Entries, depth+1)...)
 	}
 
-	return duration, title, err
+	return videos
 }
 
-func CreateDownloadCommand(url string, format string, filePath string) *exec.Cmd {
+// The process is killed when ctx is cancelled
+func CreateDownloadCommand(ctx context.Context, url string, format string, filePath string) *exec.Cmd {
    // Set the desired video quality in the format string
     if format == "" {
         format = "bestvideo[ext=mp4]+bestaudio[ext=m4a]/best[ext=mp4]"
@@ -120,13 +274,124 @@ func CreateDownloadCommand(url string, format string, filePath string) *exec.Cmd
         format = format + "+bestaudio[ext=m4a]/best[ext=mp4]"
     } 
 
-    return exec.Command("yt-dlp", "-c", "--newline", "-f", format, "-o", filePath, url)
+    // Playlist urls are expanded before they are downloaded, so a
+    // watch?v=X&list=Y url only downloads the video
+    args := append(append([]string{"-c", "--no-playlist", "-f", format, "-o", filePath}, progressTemplateArgs...), authArgs(url)...)
+
+    cmd := exec.CommandContext(ctx, "yt-dlp", append(args, url)...)
+    interruptOnCancel(cmd, interruptTimeout)
+
+    return cmd
+}
+
+// Downloads the subtitles of the video as outputPath.<language>.vtt 
+// and returns the languages that were found. With auto, only the 
+// auto generated subtitles are downloaded.
+func DownloadSubtitles(url string, languages []string, auto bool, outputPath string) ([]string, error) {
+	writeSubs := "--write-subs"
+	if auto {
+		writeSubs = "--write-auto-subs"
+	}
+
+	args := []string{
+		"--skip-download", 
+		writeSubs, 
+		"--sub-langs", strings.Join(languages, ","), 
+		"--sub-format", "vtt/best", 
+		"--convert-subs", "vtt", 
+		"-o", outputPath, 
+	}
+
+	cmd := exec.Command("yt-dlp", append(append(args, authArgs(url)...), url)...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Println("Error downloading subtitles:", url, string(output))
+		return nil, ClassifyError(err, string(output))
+	}
+
+	found := []string{}
+
+	for _, language := range languages {
+		if _, err := os.Stat(outputPath + "." + language + ".vtt"); err == nil {
+			found = append(found, language)
+		}
+	}
+
+	return found, nil
+}
+
+// Audio codecs yt-dlp can extract to (the file extension of the result)
+var AudioFormats = []string{"m4a", "mp3", "opus"}
+
+const DefaultAudioFormat = "m4a"
+
+func IsInstalled() bool {
+	_, err := exec.LookPath("yt-dlp")
+	return err == nil
 }
 
-func RunDownload(cmd *exec.Cmd, onReadProgress func(progress uint, speed string), onComplete func(), onError func(err error)) {
+func IsAudioFormat(fileFormat string) bool {
+	for _, audioFormat := range AudioFormats {
+		if audioFormat == fileFormat {
+			return true
+		}
+	}
+	return false
+}
+
+// Downloads the best audio and extracts it to audioFormat.
+// yt-dlp names the file, so the extension of filePath is 
+// replaced with the extension of the downloaded stream.
+func CreateAudioDownloadCommand(ctx context.Context, url string, audioFormat string, filePath string) *exec.Cmd {
+	if audioFormat == "" {
+		audioFormat = DefaultAudioFormat
+	}
+
+	outputTemplate := strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".%(ext)s"
+
+	args := append(append([]string{"-c", "--no-playlist", "-f", "bestaudio/best", "-x", "--audio-format", audioFormat, "-o", outputTemplate}, progressTemplateArgs...), authArgs(url)...)
+
+	cmd := exec.CommandContext(ctx, "yt-dlp", append(args, url)...)
+	interruptOnCancel(cmd, interruptTimeout)
+
+	return cmd
+}
+
+// How long yt-dlp has to exit after being interrupted before it is killed
+const interruptTimeout = 10 * time.Second
+
+// Interrupts the command (like Ctrl-C) when its context is cancelled
+// instead of killing it, so yt-dlp stops its ffmpeg processes and
+// keeps the partial files. Killed where interrupts are not supported.
+func interruptOnCancel(cmd *exec.Cmd, timeout time.Duration) {
+	detachFromTerminal(cmd)
+
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = timeout
+}
+
+// Adds --limit-rate (bytes per second) to a yt-dlp command
+func SetRateLimit(cmd *exec.Cmd, bytesPerSecond int64) {
+	args := []string{cmd.Args[0], "--limit-rate", strconv.FormatInt(bytesPerSecond, 10)}
+	cmd.Args = append(args, cmd.Args[1:]...)
+}
+
+// Runs a download command, calling onReadProgress
+// for each progress line printed by yt-dlp
+func RunDownload(cmd *exec.Cmd, onReadProgress func(progress Progress), onComplete func(), onError func(err error)) {
 	// Create a pipe to capture the output
 	stdout, _ := cmd.StdoutPipe()
 
+	// Kept to classify the error if yt-dlp fails
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
 	// Start the command
 	if err := cmd.Start(); err != nil {
 		onError(err)
@@ -137,32 +402,18 @@ func RunDownload(cmd *exec.Cmd, onReadProgress func(progress uint, speed string)
 	scanner := bufio.NewScanner(stdout)
 
 	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, "frag") && strings.HasPrefix(line, "[download]") {
-			// "[download] 0.3% of 363.35KiB at 709.34KiB/s ETA 00:00"
-			parts := strings.Fields(line)
-			if len(parts) >= 8 {
-				speed := parts[6]
-        		p := strings.TrimSuffix(parts[1], "%")
-				pf, err := strconv.ParseFloat(p, 64)
-				if err != nil {
-					onReadProgress(0, parts[6])
-				} else {
-					pf = math.Min(100, pf)
-					percentage := uint(pf)
-					onReadProgress(percentage, speed)
-				}
-	 		} 
+		if progress, ok := ParseProgressLine(scanner.Text()); ok {
+			onReadProgress(progress)
 		}
 	}
 
 	err := cmd.Wait()
 
-	if _, ok := err.(*exec.ExitError); ok {
-		// Cancelled download, or error during downloading
-	} else if err != nil {
-		// Some other error
-		onError(err)
+	if err != nil {
+		// Cancelled or paused download (process killed),
+		// or error during downloading
+		log.Println("yt-dlp download failed:", err, stderr.String())
+		onError(ClassifyError(err, stderr.String()))
 	} else {
 		onComplete()
 	}
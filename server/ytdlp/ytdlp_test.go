@@ -0,0 +1,74 @@
+package ytdlp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Behaves like yt-dlp for a watch?v=X&list=Y url: the playlist
+// is returned unless --no-playlist is passed
+const stubYtdlp = `#!/bin/sh
+for url; do :; done
+case " $* " in
+  *" --no-playlist "*)
+    case "$url" in
+      *v=*) echo '{"_type":"video","id":"X","title":"Video"}'; exit 0 ;;
+    esac ;;
+esac
+echo '{"_type":"playlist","id":"Y","title":"Playlist","entries":[{"_type":"url","id":"X","url":"https://www.youtube.com/watch?v=X"},{"_type":"url","id":"Z","url":"https://www.youtube.com/watch?v=Z"}]}'
+`
+
+// Puts the stub first in PATH so it is run instead of yt-dlp
+func useStubYtdlp(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "yt-dlp"), []byte(stubYtdlp), 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to write yt-dlp stub: %s\n", err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestGetFlatInfoVideoInPlaylist(t *testing.T) {
+	useStubYtdlp(t)
+
+	info, err := GetFlatInfo("https://www.youtube.com/watch?v=X&list=Y")
+
+	if err != nil {
+		t.Fatalf("Error getting info: %s", err)
+	}
+
+	if info.IsPlaylist() || info.ID != "X" {
+		t.Errorf("Expected only the video, got %+v", info)
+	}
+
+	info, err = GetFlatInfo("https://www.youtube.com/playlist?list=Y")
+
+	if err != nil {
+		t.Fatalf("Error getting info: %s", err)
+	}
+
+	if !info.IsPlaylist() || len(GetPlaylistVideos(*info)) != 2 {
+		t.Errorf("Expected the playlist with 2 videos, got %+v", info)
+	}
+}
+
+func TestDownloadCommandsOnlyDownloadTheVideo(t *testing.T) {
+	url := "https://www.youtube.com/watch?v=X&list=Y"
+
+	commands := [][]string{
+		CreateDownloadCommand(context.Background(), url, "22", "/tmp/video.mp4").Args,
+		CreateAudioDownloadCommand(context.Background(), url, "mp3", "/tmp/video.mp3").Args,
+	}
+
+	for _, args := range commands {
+		if !strings.Contains(strings.Join(args, " "), "--no-playlist") {
+			t.Errorf("Expected --no-playlist in %q", args)
+		}
+	}
+}